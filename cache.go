@@ -1,37 +1,193 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/bopher/caster"
+	"github.com/go-redis/redis/v8"
 )
 
 // Cache interface for cache drivers.
 type Cache interface {
-	// Put a new value to cache
+	// Put a new value to cache, item never expires if ttl is not positive
 	Put(key string, value any, ttl time.Duration) error
 	// PutForever put value with infinite ttl
 	PutForever(key string, value any) error
+	// PutMany put multiple values to cache with same ttl, items never expire if ttl is not positive
+	PutMany(values map[string]any, ttl time.Duration) error
 	// Set Change value of cache item, return false if item not exists
 	Set(key string, value any) (bool, error)
+	// Add put value if item not exists, return false if item exists. item never expires if ttl is not positive
+	Add(key string, value any, ttl time.Duration) (bool, error)
+	// CompareAndSwap change value of item if its current value equals old and keep item ttl,
+	// return false if item not exists or its value not equals old. values compared by encoded form, so values read from cache always match
+	CompareAndSwap(key string, old any, new any) (bool, error)
 	// Get item from cache
 	Get(key string) (any, error)
+	// GetMany get multiple items from cache, missing items not included in result
+	GetMany(keys []string) (map[string]any, error)
 	// Exists check if item exists in cache
 	Exists(key string) (bool, error)
 	// Forget delete Item from cache
 	Forget(key string) error
+	// ForgetMany delete multiple items from cache
+	ForgetMany(keys ...string) error
+	// Keys get keys of items matching glob pattern
+	Keys(pattern string) ([]string, error)
+	// Scan call fn for keys of items matching glob pattern until fn returns false
+	Scan(pattern string, fn func(key string) bool) error
+	// Flush delete all items of cache prefix
+	Flush() error
 	// Pull item from cache and remove it
 	Pull(key string) (any, error)
-	// TTL get cache item ttl. this method returns -1 if item not exists
+	// TTL get cache item ttl. returns NoExpiration for items without ttl and ErrNotFound if item not exists
 	TTL(key string) (time.Duration, error)
+	// Expire set item ttl, return false if item not exists. item removed if ttl is not positive
+	Expire(key string, ttl time.Duration) (bool, error)
+	// Touch reset item ttl to its last ttl set by put or expire, return false if item not exists
+	Touch(key string) (bool, error)
+	// Persist remove item ttl, return false if item not exists
+	Persist(key string) (bool, error)
 	// Cast parse cache item as caster
 	Cast(key string) (caster.Caster, error)
-	// IncrementFloat increment numeric item by float, return false if item not exists
+	// IncrementFloat increment numeric item by float, return false if item not exists and ErrNotNumeric if item is not numeric
 	IncrementFloat(key string, value float64) (bool, error)
-	// Increment increment numeric item by int, return false if item not exists
+	// Increment increment integer item by int, return false if item not exists and ErrNotNumeric if item is not integer
 	Increment(key string, value int64) (bool, error)
-	// DecrementFloat decrement numeric item by float, return false if item not exists
+	// DecrementFloat decrement numeric item by float, return false if item not exists and ErrNotNumeric if item is not numeric
 	DecrementFloat(key string, value float64) (bool, error)
-	// Decrement decrement numeric item by int, return false if item not exists
+	// Decrement decrement integer item by int, return false if item not exists and ErrNotNumeric if item is not integer
 	Decrement(key string, value int64) (bool, error)
+	// WithContext get a copy of cache driver that run all operations with ctx
+	WithContext(ctx context.Context) Cache
+}
+
+var (
+	// ErrNotFound returned when item not exists
+	ErrNotFound = errors.New("item not found")
+	// ErrNotNumeric returned by numeric operations on non numeric items
+	ErrNotNumeric = errors.New("item is not numeric")
+	// ErrNotEncrypted returned by encrypted cache for values not encrypted by wrapper
+	ErrNotEncrypted = errors.New("item is not encrypted")
+)
+
+// NoExpiration ttl of items without expiration
+const NoExpiration time.Duration = -1
+
+// SweepResult reclaimed items of sweep
+type SweepResult struct {
+	// Items removed items count
+	Items uint64
+	// Bytes removed items size in bytes
+	Bytes uint64
+}
+
+// Sweeper interface for cache drivers that can remove expired items in batch
+//
+// file and memory drivers implement this interface, tiered and encrypted wrappers forward sweep to wrapped caches
+type Sweeper interface {
+	// Sweep remove expired and corrupt items
+	Sweep() (SweepResult, error)
+}
+
+// Closer interface for cache drivers that run background cleanup
+//
+// file and memory drivers implement this interface, tiered and encrypted wrappers forward close to wrapped caches
+type Closer interface {
+	// Close stop background cleanup of driver and its context copies, driver stays usable
+	Close() error
+}
+
+// stopper close stop channel of background goroutine only once
+type stopper struct {
+	once sync.Once
+	stop chan struct{}
+}
+
+func newStopper() *stopper {
+	return &stopper{stop: make(chan struct{})}
+}
+
+// close close stop channel, nil stopper ignored
+func (s *stopper) close() {
+	if s != nil {
+		s.once.Do(func() {
+			close(s.stop)
+		})
+	}
+}
+
+// errRawUnsupported returned by rawGetter when driver can not read encoded items data
+var errRawUnsupported = errors.New("raw read not supported")
+
+// rawItem encoded item data and codec of its layer
+type rawItem struct {
+	data  []byte
+	codec codec
+}
+
+// rawGetter interface for drivers that can read encoded items data
+type rawGetter interface {
+	// getRaw get encoded item data and its codec, data is nil if item not exists
+	getRaw(key string) ([]byte, codec, error)
+	// getManyRaw get encoded data and codec of multiple items, missing items not included in result
+	getManyRaw(keys []string) (map[string]rawItem, error)
+}
+
+// codecer interface for drivers that encode values with codec, values returned by Get decoded with codec
+type codecer interface {
+	// valueCodec get codec of values, return false if values not decoded with one codec
+	valueCodec() (codec, bool)
+}
+
+// scripter interface for drivers that run lua scripts atomically
+//
+// redis driver implement this interface
+type scripter interface {
+	// runScript run script on prefixed keys
+	runScript(script *redis.Script, keys []string, args ...any) (any, error)
+}
+
+// ttlGetter interface for drivers that can read ttl of multiple items in batch
+//
+// redis driver implement this interface, tiered and encrypted wrappers read ttls of their remote or underlying cache
+type ttlGetter interface {
+	// ttlMany get ttl of multiple items, missing items not included in result
+	ttlMany(keys []string) (map[string]time.Duration, error)
+}
+
+// ttlMany get ttl of multiple items, read in batch if cache implement ttlGetter. missing items not included in result
+func ttlMany(c Cache, keys []string) (map[string]time.Duration, error) {
+	if g, ok := c.(ttlGetter); ok {
+		return g.ttlMany(keys)
+	}
+
+	res := make(map[string]time.Duration, len(keys))
+	for _, key := range keys {
+		ttl, err := c.TTL(key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		res[key] = ttl
+	}
+	return res, nil
+}
+
+// collectKeys collect keys of scan
+func collectKeys(c Cache, pattern string) ([]string, error) {
+	keys := make([]string, 0)
+	seen := make(map[string]struct{})
+	err := c.Scan(pattern, func(key string) bool {
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return keys, err
 }
@@ -0,0 +1,510 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bopher/caster"
+	"github.com/bopher/utils"
+)
+
+// prefix of encrypted values, followed by key id, separator and base64 encoded nonce and ciphertext
+const encryptedPrefix = "enc1:"
+
+// maximum compare and swap attempts of update, update fails if item changed by others on all attempts
+const maxUpdateAttempts = 100
+
+type eCache struct {
+	cache     Cache
+	codec     codec
+	current   string
+	keys      map[string]cipher.AEAD
+	plaintext bool
+}
+
+func (ec eCache) err(pattern string, params ...any) error {
+	return utils.TaggedError([]string{"EncryptedCache"}, pattern, params...)
+}
+
+func (ec *eCache) init(cache Cache, keyID string, keys map[string][]byte, conf config) error {
+	if cache == nil {
+		return ec.err("cache driver is nil")
+	}
+
+	if _, ok := keys[keyID]; !ok {
+		return ec.err("encryption key %s not found", keyID)
+	}
+
+	ec.cache = cache
+	ec.codec = newCodec(conf)
+	ec.current = keyID
+	ec.plaintext = conf.plaintext
+	ec.keys = make(map[string]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return ec.err("invalid key id %q", id)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return ec.err("key %s: %w", id, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return ec.err("key %s: %w", id, err)
+		}
+		ec.keys[id] = aead
+	}
+	return nil
+}
+
+// seal encode and encrypt value with current key, item key used as additional data
+// so encrypted values can not be moved between keys
+func (ec eCache) seal(key string, value any) (string, error) {
+	plain, err := ec.codec.encode(value)
+	if err != nil {
+		return "", ec.err("%w", err)
+	}
+
+	aead := ec.keys[ec.current]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", ec.err("%w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plain, []byte(key))
+	return encryptedPrefix + ec.current + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypt stored value and return encoded plain data. values not encrypted by wrapper
+// rejected with ErrNotEncrypted, or return false if plaintext fallback enabled
+func (ec eCache) open(key string, value any) ([]byte, bool, error) {
+	str, ok := value.(string)
+	if !ok || !strings.HasPrefix(str, encryptedPrefix) {
+		if ec.plaintext {
+			return nil, false, nil
+		}
+		return nil, false, ec.err("%s: %w", key, ErrNotEncrypted)
+	}
+
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(str, encryptedPrefix), ":")
+	if !ok {
+		return nil, true, ec.err("%s: invalid encrypted value", key)
+	}
+
+	aead, ok := ec.keys[id]
+	if !ok {
+		return nil, true, ec.err("%s: encryption key %s not found", key, id)
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, true, ec.err("%s: invalid encrypted value", key)
+	}
+
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(key))
+	if err != nil {
+		return nil, true, ec.err("%s: %w", key, err)
+	}
+	return plain, true, nil
+}
+
+// decrypt decrypt and decode stored value, values not encrypted by wrapper returned as is if plaintext fallback enabled
+func (ec eCache) decrypt(key string, value any) (any, error) {
+	plain, encrypted, err := ec.open(key, value)
+	if err != nil || !encrypted {
+		return value, err
+	}
+
+	if v, err := ec.codec.decode(plain); err != nil {
+		return nil, ec.err("%s: %w", key, err)
+	} else {
+		return v, nil
+	}
+}
+
+// update change item value with fn and keep item ttl, return false if item not exists.
+// fn called with number of encrypted value (nil for non numeric values) or values not encrypted by wrapper as is,
+// stored value swapped with compare and swap and fn retried at most maxUpdateAttempts times if item changed by others
+func (ec eCache) update(key string, fn func(v any) (any, error)) (bool, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		stored, err := ec.cache.Get(key)
+		if err != nil {
+			return false, ec.err("%w", err)
+		}
+
+		if stored == nil {
+			return false, nil
+		}
+
+		plain, encrypted, err := ec.open(key, stored)
+		if err != nil {
+			return false, err
+		}
+
+		v := stored
+		if encrypted {
+			v, _ = numberOf(plain)
+		}
+
+		if v, err = fn(v); err != nil {
+			return false, ec.err("%w", err)
+		}
+
+		sealed, err := ec.seal(key, v)
+		if err != nil {
+			return false, err
+		}
+
+		if swapped, err := ec.cache.CompareAndSwap(key, stored, sealed); err != nil {
+			return false, ec.err("%w", err)
+		} else if swapped {
+			return true, nil
+		}
+	}
+	return false, ec.err("%s: swap failed after %d attempts", key, maxUpdateAttempts)
+}
+
+func (ec eCache) Put(key string, value any, ttl time.Duration) error {
+	sealed, err := ec.seal(key, value)
+	if err != nil {
+		return err
+	}
+
+	if err := ec.cache.Put(key, sealed, ttl); err != nil {
+		return ec.err("%w", err)
+	}
+	return nil
+}
+
+func (ec eCache) PutForever(key string, value any) error {
+	sealed, err := ec.seal(key, value)
+	if err != nil {
+		return err
+	}
+
+	if err := ec.cache.PutForever(key, sealed); err != nil {
+		return ec.err("%w", err)
+	}
+	return nil
+}
+
+func (ec eCache) PutMany(values map[string]any, ttl time.Duration) error {
+	sealed := make(map[string]any, len(values))
+	for k, v := range values {
+		if s, err := ec.seal(k, v); err != nil {
+			return err
+		} else {
+			sealed[k] = s
+		}
+	}
+
+	if err := ec.cache.PutMany(sealed, ttl); err != nil {
+		return ec.err("%w", err)
+	}
+	return nil
+}
+
+func (ec eCache) Set(key string, value any) (bool, error) {
+	sealed, err := ec.seal(key, value)
+	if err != nil {
+		return false, err
+	}
+
+	if exists, err := ec.cache.Set(key, sealed); err != nil {
+		return false, ec.err("%w", err)
+	} else {
+		return exists, nil
+	}
+}
+
+func (ec eCache) Add(key string, value any, ttl time.Duration) (bool, error) {
+	sealed, err := ec.seal(key, value)
+	if err != nil {
+		return false, err
+	}
+
+	if ok, err := ec.cache.Add(key, sealed, ttl); err != nil {
+		return false, ec.err("%w", err)
+	} else {
+		return ok, nil
+	}
+}
+
+// CompareAndSwap compare decrypted value with old and swap stored encrypted value atomically by underlying cache
+func (ec eCache) CompareAndSwap(key string, old any, new any) (bool, error) {
+	stored, err := ec.cache.Get(key)
+	if err != nil {
+		return false, ec.err("%w", err)
+	}
+
+	if stored == nil {
+		return false, nil
+	}
+
+	plain, encrypted, err := ec.open(key, stored)
+	if err != nil {
+		return false, err
+	}
+
+	encoded, err := ec.codec.encode(old)
+	if err != nil {
+		return false, ec.err("%w", err)
+	}
+
+	if encrypted && !bytes.Equal(plain, encoded) {
+		return false, nil
+	}
+
+	sealed, err := ec.seal(key, new)
+	if err != nil {
+		return false, err
+	}
+
+	if !encrypted {
+		// value written before enabling encryption compared by underlying cache
+		stored = old
+	}
+
+	if swapped, err := ec.cache.CompareAndSwap(key, stored, sealed); err != nil {
+		return false, ec.err("%w", err)
+	} else {
+		return swapped, nil
+	}
+}
+
+func (ec eCache) Get(key string) (any, error) {
+	v, err := ec.cache.Get(key)
+	if err != nil {
+		return nil, ec.err("%w", err)
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+	return ec.decrypt(key, v)
+}
+
+func (ec eCache) GetMany(keys []string) (map[string]any, error) {
+	items, err := ec.cache.GetMany(keys)
+	if err != nil {
+		return nil, ec.err("%w", err)
+	}
+
+	res := make(map[string]any, len(items))
+	for k, v := range items {
+		if res[k], err = ec.decrypt(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (ec eCache) Exists(key string) (bool, error) {
+	if exists, err := ec.cache.Exists(key); err != nil {
+		return false, ec.err("%w", err)
+	} else {
+		return exists, nil
+	}
+}
+
+func (ec eCache) Forget(key string) error {
+	if err := ec.cache.Forget(key); err != nil {
+		return ec.err("%w", err)
+	}
+	return nil
+}
+
+func (ec eCache) ForgetMany(keys ...string) error {
+	if err := ec.cache.ForgetMany(keys...); err != nil {
+		return ec.err("%w", err)
+	}
+	return nil
+}
+
+func (ec eCache) Keys(pattern string) ([]string, error) {
+	if keys, err := ec.cache.Keys(pattern); err != nil {
+		return nil, ec.err("%w", err)
+	} else {
+		return keys, nil
+	}
+}
+
+func (ec eCache) Scan(pattern string, fn func(key string) bool) error {
+	if err := ec.cache.Scan(pattern, fn); err != nil {
+		return ec.err("%w", err)
+	}
+	return nil
+}
+
+func (ec eCache) Flush() error {
+	if err := ec.cache.Flush(); err != nil {
+		return ec.err("%w", err)
+	}
+	return nil
+}
+
+func (ec eCache) Pull(key string) (any, error) {
+	v, err := ec.cache.Pull(key)
+	if err != nil {
+		return nil, ec.err("%w", err)
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+	return ec.decrypt(key, v)
+}
+
+func (ec eCache) TTL(key string) (time.Duration, error) {
+	if ttl, err := ec.cache.TTL(key); err != nil {
+		return 0, ec.err("%w", err)
+	} else {
+		return ttl, nil
+	}
+}
+
+func (ec eCache) Expire(key string, ttl time.Duration) (bool, error) {
+	if exists, err := ec.cache.Expire(key, ttl); err != nil {
+		return false, ec.err("%w", err)
+	} else {
+		return exists, nil
+	}
+}
+
+func (ec eCache) Touch(key string) (bool, error) {
+	if exists, err := ec.cache.Touch(key); err != nil {
+		return false, ec.err("%w", err)
+	} else {
+		return exists, nil
+	}
+}
+
+func (ec eCache) Persist(key string) (bool, error) {
+	if exists, err := ec.cache.Persist(key); err != nil {
+		return false, ec.err("%w", err)
+	} else {
+		return exists, nil
+	}
+}
+
+func (ec eCache) Cast(key string) (caster.Caster, error) {
+	v, err := ec.Get(key)
+	return caster.NewCaster(v), err
+}
+
+func (ec eCache) IncrementFloat(key string, value float64) (bool, error) {
+	return ec.update(key, func(v any) (any, error) {
+		if f, ok := toFloat64(v); !ok {
+			return nil, fmt.Errorf("%s: %w", key, ErrNotNumeric)
+		} else {
+			return f + value, nil
+		}
+	})
+}
+
+func (ec eCache) Increment(key string, value int64) (bool, error) {
+	return ec.update(key, func(v any) (any, error) {
+		if i, ok := toInt64(v); !ok {
+			return nil, fmt.Errorf("%s: %w", key, ErrNotNumeric)
+		} else {
+			return i + value, nil
+		}
+	})
+}
+
+func (ec eCache) DecrementFloat(key string, value float64) (bool, error) {
+	return ec.IncrementFloat(key, -value)
+}
+
+func (ec eCache) Decrement(key string, value int64) (bool, error) {
+	return ec.Increment(key, -value)
+}
+
+// Close stop background cleanup of underlying cache if it implements Closer
+func (ec eCache) Close() error {
+	if closer, ok := ec.cache.(Closer); ok {
+		if err := closer.Close(); err != nil {
+			return ec.err("%w", err)
+		}
+	}
+	return nil
+}
+
+// Sweep remove expired items of underlying cache if it implements Sweeper
+func (ec eCache) Sweep() (SweepResult, error) {
+	if sweeper, ok := ec.cache.(Sweeper); ok {
+		if res, err := sweeper.Sweep(); err != nil {
+			return res, ec.err("%w", err)
+		} else {
+			return res, nil
+		}
+	}
+	return SweepResult{}, nil
+}
+
+// ttlMany get ttl of multiple items of underlying cache
+func (ec eCache) ttlMany(keys []string) (map[string]time.Duration, error) {
+	if res, err := ttlMany(ec.cache, keys); err != nil {
+		return nil, ec.err("%w", err)
+	} else {
+		return res, nil
+	}
+}
+
+// getRaw get decrypted encoded data, values not encrypted by wrapper not supported
+func (ec eCache) valueCodec() (codec, bool) {
+	return ec.codec, true
+}
+
+func (ec eCache) getRaw(key string) ([]byte, codec, error) {
+	v, err := ec.cache.Get(key)
+	if err != nil {
+		return nil, ec.codec, ec.err("%w", err)
+	}
+
+	if v == nil {
+		return nil, ec.codec, nil
+	}
+
+	plain, encrypted, err := ec.open(key, v)
+	if err == nil && !encrypted {
+		err = errRawUnsupported
+	}
+	return plain, ec.codec, err
+}
+
+func (ec eCache) getManyRaw(keys []string) (map[string]rawItem, error) {
+	items, err := ec.cache.GetMany(keys)
+	if err != nil {
+		return nil, ec.err("%w", err)
+	}
+
+	res := make(map[string]rawItem, len(items))
+	for k, v := range items {
+		plain, encrypted, err := ec.open(k, v)
+		if err == nil && !encrypted {
+			err = errRawUnsupported
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		res[k] = rawItem{data: plain, codec: ec.codec}
+	}
+	return res, nil
+}
+
+func (ec eCache) WithContext(ctx context.Context) Cache {
+	ec.cache = ec.cache.WithContext(ctx)
+	return &ec
+}
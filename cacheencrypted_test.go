@@ -0,0 +1,316 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bopher/cache"
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	encKeyV1 = bytes.Repeat([]byte{1}, 32)
+	encKeyV2 = bytes.Repeat([]byte{2}, 16)
+)
+
+func encryptedCache(t *testing.T, c cache.Cache, keyID string, keys map[string][]byte, options ...cache.Option) cache.Cache {
+	ec, err := cache.NewEncryptedCache(c, keyID, keys, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ec
+}
+
+func TestEncryptedCache(t *testing.T) {
+	dir := t.TempDir()
+	ec := encryptedCache(t, cache.NewFileCache("enc", dir), "v1", map[string][]byte{"v1": encKeyV1})
+
+	err := ec.Put("secret", "my-session-token", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := ec.Get("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "my-session-token" {
+		t.Fatalf("failed encrypted round-trip %v", v)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		content, err := os.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bytes.Contains(content, []byte("my-session-token")) {
+			t.Fatal("plain value stored on disk")
+		}
+	}
+
+	err = ec.Put("counter", 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := ec.Increment("counter", 2)
+	if err != nil || !ok {
+		t.Fatal("failed increment", err)
+	}
+
+	v, err = ec.Get("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != int64(5) {
+		t.Fatalf("failed encrypted increment %v", v)
+	}
+}
+
+func TestEncryptedCacheRotation(t *testing.T) {
+	mc := cache.NewMemoryCache(0, 0, 0)
+	old := encryptedCache(t, mc, "v1", map[string][]byte{"v1": encKeyV1})
+	err := old.Put("user", "john", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := encryptedCache(t, mc, "v2", map[string][]byte{"v1": encKeyV1, "v2": encKeyV2})
+	v, err := rotated.Get("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "john" {
+		t.Fatalf("failed read old key value %v", v)
+	}
+
+	_, err = encryptedCache(t, mc, "v2", map[string][]byte{"v2": encKeyV2}).Get("user")
+	if err == nil {
+		t.Fatal("value of removed key decrypted")
+	}
+
+	// encrypted value bound to its key
+	raw, err := mc.Get("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mc.Put("other", raw, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = rotated.Get("other")
+	if err == nil {
+		t.Fatal("moved value decrypted")
+	}
+
+	_, err = cache.NewEncryptedCache(mc, "v1", map[string][]byte{"v1": []byte("short")})
+	if err == nil {
+		t.Fatal("invalid key accepted")
+	}
+}
+
+func TestEncryptedCachePlaintext(t *testing.T) {
+	mc := cache.NewMemoryCache(0, 0, 0)
+	err := mc.Put("role", "admin", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ec := encryptedCache(t, mc, "v1", map[string][]byte{"v1": encKeyV1})
+	if _, err := ec.Get("role"); !errors.Is(err, cache.ErrNotEncrypted) {
+		t.Fatal("plain value accepted", err)
+	}
+
+	if _, err := ec.CompareAndSwap("role", "admin", "root"); !errors.Is(err, cache.ErrNotEncrypted) {
+		t.Fatal("plain value swapped", err)
+	}
+
+	fallback := encryptedCache(t, mc, "v1", map[string][]byte{"v1": encKeyV1}, cache.WithPlaintextFallback())
+	v, err := fallback.Get("role")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "admin" {
+		t.Fatalf("failed plaintext fallback %v", v)
+	}
+
+	swapped, err := fallback.CompareAndSwap("role", "admin", "user")
+	if err != nil || !swapped {
+		t.Fatal("failed swap plain value", err)
+	}
+
+	if v, err := ec.Get("role"); err != nil || v != "user" {
+		t.Fatalf("plain value not re-encrypted %v %v", v, err)
+	}
+}
+
+// conflictCache cache that never swap values
+type conflictCache struct {
+	cache.Cache
+}
+
+func (conflictCache) CompareAndSwap(key string, old any, new any) (bool, error) {
+	return false, nil
+}
+
+func TestEncryptedCachePlaintextNumbers(t *testing.T) {
+	rc := redisCache()
+	err := rc.Put("enc-number", 5, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// plain values stored by older versions
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	err = client.Set(context.Background(), "test-enc-legacy", "5", time.Minute).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Set(context.Background(), "test-enc-name", "kim", time.Minute).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ec := encryptedCache(t, rc, "v1", map[string][]byte{"v1": encKeyV1}, cache.WithPlaintextFallback())
+	for _, key := range []string{"enc-number", "enc-legacy"} {
+		ok, err := ec.Increment(key, 1)
+		if err != nil || !ok {
+			t.Fatal("failed increment plain number", key, err)
+		}
+
+		if v, err := ec.Get(key); err != nil || v != int64(6) {
+			t.Fatalf("failed incremented plain number %s %#v %v", key, v, err)
+		}
+	}
+
+	swapped, err := ec.CompareAndSwap("enc-name", "kim", "john")
+	if err != nil || !swapped {
+		t.Fatal("failed swap plain value", err)
+	}
+
+	if v, err := ec.Get("enc-name"); err != nil || v != "john" {
+		t.Fatalf("failed swapped plain value %v %v", v, err)
+	}
+
+	conflicted := encryptedCache(t, conflictCache{rc}, "v1", map[string][]byte{"v1": encKeyV1})
+	if ok, err := conflicted.Increment("enc-number", 1); err == nil || ok {
+		t.Fatal("increment without progress succeeded")
+	}
+}
+
+func TestEncryptedCacheTyped(t *testing.T) {
+	ec := encryptedCache(t, redisCache(), "v1", map[string][]byte{"v1": encKeyV1}, cache.WithSerializer(cache.JSONSerializer()))
+	typed := cache.NewTyped[typedProduct](ec, 0)
+	err := typed.Put("enc-product", typedProduct{Name: "pen", Tags: []string{"blue"}}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, exists, err := typed.Get("enc-product")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists || v.Name != "pen" || len(v.Tags) != 1 {
+		t.Fatalf("failed typed encrypted read %#v", v)
+	}
+}
+
+func TestEncryptedCacheCompareAndSwap(t *testing.T) {
+	ec := encryptedCache(t, redisCache(), "v1", map[string][]byte{"v1": encKeyV1})
+	err := ec.Put("enc-cas", "a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := ec.CompareAndSwap("enc-cas", "b", "c")
+	if err != nil || ok {
+		t.Fatal("failed compare and swap with wrong old", err)
+	}
+
+	ok, err = ec.CompareAndSwap("enc-cas", "a", "c")
+	if err != nil || !ok {
+		t.Fatal("failed compare and swap", err)
+	}
+
+	err = ec.Put("enc-counter", 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ec.Increment("enc-counter", 1); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	v, err := ec.Get("enc-counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != int64(20) {
+		t.Fatalf("lost encrypted increments %v", v)
+	}
+}
+
+func TestEncryptedCacheSweepClose(t *testing.T) {
+	c := encryptedCache(t, cache.NewMemoryCache(100, 0, time.Minute), "v1", map[string][]byte{"v1": encKeyV1})
+	err := c.Put("enc-name", "kim", 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	res, err := c.(cache.Sweeper).Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Items != 1 {
+		t.Fatalf("failed sweep underlying cache %d", res.Items)
+	}
+
+	if err := c.(cache.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// drivers without background cleanup ignored
+	rc := encryptedCache(t, redisCache(), "v1", map[string][]byte{"v1": encKeyV1})
+	if res, err := rc.(cache.Sweeper).Sweep(); err != nil || res.Items != 0 {
+		t.Fatal("failed sweep redis cache", res, err)
+	}
+
+	if err := rc.(cache.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+}
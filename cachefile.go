@@ -1,31 +1,85 @@
 package cache
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"errors"
 	"io/ioutil"
-	"math"
 	"os"
 	"path"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bopher/caster"
 	"github.com/bopher/utils"
 )
 
+const (
+	// max concurrent file operations in batch methods
+	fileWorkers = 16
+	// directory of item lock files inside cache directory
+	lockDir = ".locks"
+	// temp file name pattern for atomic writes
+	tempPattern = ".tmp-*"
+	// temp files older than this are leftovers of crashed writers
+	tempStaleAfter = time.Hour
+)
+
+// item file name pattern
+var itemFileRx = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
 type fCache struct {
-	prefix string
-	dir    string
+	prefix  string
+	dir     string
+	codec   codec
+	ctx     context.Context
+	stopper *stopper
 }
 
 func (rc fCache) err(pattern string, params ...any) error {
 	return utils.TaggedError([]string{"FileCache"}, pattern, params...)
 }
 
-func (rc *fCache) init(prefix string, dir string) {
+func (rc *fCache) init(prefix string, dir string, conf config) {
 	rc.prefix = prefix
 	rc.dir = dir
+	rc.codec = newCodec(conf)
+	rc.ctx = context.Background()
+
+	if conf.sweepInterval > 0 {
+		rc.stopper = newStopper()
+		go sweepEvery(*rc, conf.sweepInterval, rc.stopper.stop)
+	}
+}
+
+// Close stop background sweep of cache and its context copies
+func (rc fCache) Close() error {
+	rc.stopper.close()
+	return nil
+}
+
+// sweepEvery run sweep every interval until stop closed
+func sweepEvery(rc fCache, interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			rc.Sweep()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (rc fCache) canceled() error {
+	if err := rc.ctx.Err(); err != nil {
+		return rc.err("%w", err)
+	}
+	return nil
 }
 
 func (rc fCache) hashPath(key string) string {
@@ -36,82 +90,302 @@ func (rc fCache) hashPath(key string) string {
 	return fileName
 }
 
+// lockPath get lock file path of item file, items share a limited number of lock files
+func (rc fCache) lockPath(file string) string {
+	return path.Join(rc.dir, lockDir, path.Base(file)[:2])
+}
+
+// locked run fn while holding advisory lock of key
+func (rc fCache) locked(key string, fn func() error) error {
+	return rc.lockedFile(rc.hashPath(key), fn)
+}
+
+// lockedFile run fn while holding advisory lock of item file
+func (rc fCache) lockedFile(file string, fn func() error) error {
+	if err := rc.canceled(); err != nil {
+		return err
+	}
+
+	err := utils.CreateDirectory(path.Join(rc.dir, lockDir))
+	if err != nil {
+		return rc.err("%w", err)
+	}
+
+	unlock, err := lockFile(rc.lockPath(file))
+	if err != nil {
+		return rc.err("%w", err)
+	}
+	defer unlock()
+
+	return fn()
+}
+
+// delete remove item file, caller must hold key lock
 func (rc fCache) delete(key string) error {
 	if err := os.Remove(rc.hashPath(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
-		return rc.err(err.Error())
+		return rc.err("%w", err)
 	}
 	return nil
 }
 
-func (rc fCache) read(key string) (*record, error) {
+// decode read item record file, return nil if file not exists
+func (rc fCache) decode(key string) (*record, error) {
+	if err := rc.canceled(); err != nil {
+		return nil, err
+	}
+
 	bytes, err := ioutil.ReadFile(rc.hashPath(key))
 	if errors.Is(err, os.ErrNotExist) {
 		return nil, nil
 	}
 
 	if err != nil {
-		return nil, rc.err(err.Error())
+		return nil, rc.err("%w", err)
 	}
 
 	rec := record{}
-	if err := rec.Deserialize(string(bytes)); err != nil {
-		return nil, rc.err(err.Error())
+	if err := rec.Deserialize(rc.codec, bytes); err != nil {
+		return nil, rc.err("%w", err)
+	}
+	return &rec, nil
+}
+
+// readLocked read item record and remove expired item file, caller must hold key lock
+func (rc fCache) readLocked(key string) (*record, error) {
+	rec, err := rc.decode(key)
+	if err != nil || rec == nil {
+		return nil, err
 	}
 
 	if rec.IsExpired() {
-		err := rc.delete(key)
-		if err != nil {
-			err = rc.err(err.Error())
-		}
+		return nil, rc.delete(key)
+	}
+	return rec, nil
+}
+
+// read read item record without lock, expired item file removed under lock
+func (rc fCache) read(key string) (*record, error) {
+	rec, err := rc.decode(key)
+	if err != nil || rec == nil {
 		return nil, err
 	}
 
-	return &rec, nil
+	if rec.IsExpired() {
+		return nil, rc.locked(key, func() error {
+			_, err := rc.readLocked(key)
+			return err
+		})
+	}
+	return rec, nil
+}
+
+// readHeaderFile decode header of item file without reading item value, return nil if file not exists
+func (rc fCache) readHeaderFile(file string) (*recordHeader, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header, err := decodeHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	return &header, nil
 }
 
+// header read item record header without lock, expired item file removed under lock
+func (rc fCache) header(key string) (*recordHeader, error) {
+	if err := rc.canceled(); err != nil {
+		return nil, err
+	}
+
+	header, err := rc.readHeaderFile(rc.hashPath(key))
+	if err != nil {
+		return nil, rc.err("%w", err)
+	}
+
+	if header != nil && header.IsExpired() {
+		return nil, rc.locked(key, func() error {
+			_, err := rc.readLocked(key)
+			return err
+		})
+	}
+	return header, nil
+}
+
+// write write item record to temp file and rename it to item file, so readers never see partial file.
+// caller must hold key lock
 func (rc fCache) write(key string, record record) error {
 	err := utils.CreateDirectory(rc.dir)
 	if err != nil {
-		return rc.err(err.Error())
+		return rc.err("%w", err)
+	}
+
+	record.Prefix = rc.prefix
+	record.Key = key
+	encoded, err := record.Serialize(rc.codec)
+	if err != nil {
+		return rc.err("%w", err)
 	}
+	return rc.writeFile(key, encoded)
+}
 
-	encoded, err := record.Serialize()
+// writeFile write item file content to temp file and rename it to item file, caller must hold key lock
+func (rc fCache) writeFile(key string, encoded []byte) error {
+	tmp, err := ioutil.TempFile(rc.dir, tempPattern)
 	if err != nil {
-		return rc.err(err.Error())
+		return rc.err("%w", err)
 	}
+	defer os.Remove(tmp.Name())
 
-	err = ioutil.WriteFile(rc.hashPath(key), []byte(encoded), 0644)
+	_, err = tmp.Write(encoded)
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
 	if err != nil {
-		return rc.err(err.Error())
+		return rc.err("%w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return rc.err("%w", err)
 	}
 
+	if err := os.Rename(tmp.Name(), rc.hashPath(key)); err != nil {
+		return rc.err("%w", err)
+	}
 	return nil
 }
 
-func (rc fCache) Put(key string, value any, ttl time.Duration) error {
-	rec := record{
-		TTL:  time.Now().UTC().Add(ttl),
-		Data: value,
+// update change item value with fn and keep item ttl, return false if item not exists
+func (rc fCache) update(key string, fn func(rec *record) error) (bool, error) {
+	exists := false
+	err := rc.locked(key, func() error {
+		rec, err := rc.readLocked(key)
+		if err != nil || rec == nil {
+			return err
+		}
+
+		exists = true
+		if err := fn(rec); err != nil {
+			return err
+		}
+		return rc.write(key, *rec)
+	})
+	return exists, err
+}
+
+// parallel run fn for keys concurrently and return first error
+func (rc fCache) parallel(keys []string, fn func(key string) error) error {
+	var firstErr error
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, fileWorkers)
+	for _, k := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(key string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(key); err != nil {
+				mutex.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mutex.Unlock()
+			}
+		}(k)
 	}
-	return rc.write(key, rec)
+	wg.Wait()
+	return firstErr
+}
+
+// newRecord create record of value with ttl, non-positive ttl never expires
+func newRecord(value any, ttl time.Duration) record {
+	if ttl <= 0 {
+		return record{TTL: foreverTTL, Data: value}
+	}
+
+	return record{
+		TTL:      time.Now().UTC().Add(ttl),
+		Lifetime: ttl,
+		Data:     value,
+	}
+}
+
+func (rc fCache) Put(key string, value any, ttl time.Duration) error {
+	rec := newRecord(value, ttl)
+	return rc.locked(key, func() error {
+		return rc.write(key, rec)
+	})
 }
 
 func (rc fCache) PutForever(key string, value any) error {
 	rec := record{
-		TTL:  time.Unix(math.MaxInt64, 0),
+		TTL:  foreverTTL,
 		Data: value,
 	}
-	return rc.write(key, rec)
+	return rc.locked(key, func() error {
+		return rc.write(key, rec)
+	})
+}
+
+func (rc fCache) PutMany(values map[string]any, ttl time.Duration) error {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+
+	return rc.parallel(keys, func(key string) error {
+		return rc.Put(key, values[key], ttl)
+	})
 }
 
 func (rc fCache) Set(key string, value any) (bool, error) {
-	rec, err := rc.read(key)
-	if err != nil || rec == nil {
-		return false, err
+	return rc.update(key, func(rec *record) error {
+		rec.Data = value
+		return nil
+	})
+}
+
+func (rc fCache) Add(key string, value any, ttl time.Duration) (bool, error) {
+	added := false
+	err := rc.locked(key, func() error {
+		rec, err := rc.readLocked(key)
+		if err != nil || rec != nil {
+			return err
+		}
+
+		added = true
+		return rc.write(key, newRecord(value, ttl))
+	})
+	return added, err
+}
+
+func (rc fCache) CompareAndSwap(key string, old any, new any) (bool, error) {
+	encoded, err := rc.codec.encode(old)
+	if err != nil {
+		return false, rc.err("%w", err)
 	}
 
-	rec.Data = value
-	return true, rc.write(key, *rec)
+	swapped := false
+	err = rc.locked(key, func() error {
+		rec, err := rc.readLocked(key)
+		if err != nil || rec == nil || !matchStored(rec.value, encoded, old) {
+			return err
+		}
+
+		swapped = true
+		rec.Data = new
+		return rc.write(key, *rec)
+	})
+	return swapped, err
 }
 
 func (rc fCache) Get(key string) (any, error) {
@@ -123,30 +397,131 @@ func (rc fCache) Get(key string) (any, error) {
 	return rec.Data, nil
 }
 
+func (rc fCache) GetMany(keys []string) (map[string]any, error) {
+	res := make(map[string]any)
+	mutex := sync.Mutex{}
+	err := rc.parallel(keys, func(key string) error {
+		rec, err := rc.read(key)
+		if err != nil || rec == nil {
+			return err
+		}
+
+		mutex.Lock()
+		res[key] = rec.Data
+		mutex.Unlock()
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (rc fCache) Exists(key string) (bool, error) {
-	rec, err := rc.read(key)
-	return rec == nil, err
+	header, err := rc.header(key)
+	return header != nil, err
 }
 
 func (rc fCache) Forget(key string) error {
-	return rc.delete(key)
+	return rc.locked(key, func() error {
+		return rc.delete(key)
+	})
+}
+
+func (rc fCache) ForgetMany(keys ...string) error {
+	return rc.parallel(keys, rc.Forget)
 }
 
 func (rc fCache) Pull(key string) (any, error) {
-	if v, err := rc.Get(key); err != nil {
+	var v any
+	err := rc.locked(key, func() error {
+		rec, err := rc.readLocked(key)
+		if err != nil || rec == nil {
+			return err
+		}
+
+		v = rec.Data
+		return rc.delete(key)
+	})
+
+	if err != nil {
 		return nil, err
-	} else {
-		return v, rc.delete(key)
 	}
+	return v, nil
 }
 
 func (rc fCache) TTL(key string) (time.Duration, error) {
-	rec, err := rc.read(key)
-	if err != nil || rec == nil {
-		return -1, err
+	header, err := rc.header(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if header == nil {
+		return 0, rc.err("%s: %w", key, ErrNotFound)
+	}
+
+	if !header.TTL.Before(foreverTTL) {
+		return NoExpiration, nil
 	}
+	return header.TTL.UTC().Sub(time.Now().UTC()), nil
+}
+
+// expire change item header with fn and rewrite item file without decoding value,
+// item removed if fn returns false. return false if item not exists
+func (rc fCache) expire(key string, fn func(header *recordHeader) bool) (bool, error) {
+	exists := false
+	err := rc.locked(key, func() error {
+		data, err := ioutil.ReadFile(rc.hashPath(key))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		if err != nil {
+			return rc.err("%w", err)
+		}
 
-	return rec.TTL.UTC().Sub(time.Now().UTC()), nil
+		header, payload, err := splitRecord(rc.codec, data)
+		if err != nil {
+			return rc.err("%w", err)
+		}
+
+		if header.IsExpired() {
+			return rc.delete(key)
+		}
+
+		exists = true
+		if !fn(&header) {
+			return rc.delete(key)
+		}
+		return rc.writeFile(key, header.encode(payload))
+	})
+	return exists, err
+}
+
+func (rc fCache) Expire(key string, ttl time.Duration) (bool, error) {
+	return rc.expire(key, func(header *recordHeader) bool {
+		header.TTL = time.Now().UTC().Add(ttl)
+		header.Lifetime = ttl
+		return ttl > 0
+	})
+}
+
+func (rc fCache) Touch(key string) (bool, error) {
+	return rc.expire(key, func(header *recordHeader) bool {
+		if header.Lifetime > 0 {
+			header.TTL = time.Now().UTC().Add(header.Lifetime)
+		}
+		return true
+	})
+}
+
+func (rc fCache) Persist(key string) (bool, error) {
+	return rc.expire(key, func(header *recordHeader) bool {
+		header.TTL = foreverTTL
+		header.Lifetime = 0
+		return true
+	})
 }
 
 func (rc fCache) Cast(key string) (caster.Caster, error) {
@@ -155,49 +530,231 @@ func (rc fCache) Cast(key string) (caster.Caster, error) {
 }
 
 func (rc fCache) IncrementFloat(key string, value float64) (bool, error) {
-	if c, err := rc.Cast(key); err != nil {
-		return false, err
-	} else {
-		if v, err := c.Float64(); err != nil {
-			return false, rc.err(err.Error())
+	return rc.update(key, func(rec *record) error {
+		current, _ := numberOf(rec.value)
+		if v, ok := toFloat64(current); !ok {
+			return rc.err("%s: %w", key, ErrNotNumeric)
 		} else {
-			return rc.Set(key, v+value)
+			rec.Data = v + value
+			return nil
 		}
-	}
+	})
 }
 
 func (rc fCache) Increment(key string, value int64) (bool, error) {
-	if c, err := rc.Cast(key); err != nil {
-		return false, err
-	} else {
-		if v, err := c.Int64(); err != nil {
-			return false, rc.err(err.Error())
+	return rc.update(key, func(rec *record) error {
+		current, _ := numberOf(rec.value)
+		if v, ok := toInt64(current); !ok {
+			return rc.err("%s: %w", key, ErrNotNumeric)
 		} else {
-			return rc.Set(key, v+value)
+			rec.Data = v + value
+			return nil
 		}
-	}
+	})
 }
 
 func (rc fCache) DecrementFloat(key string, value float64) (bool, error) {
-	if c, err := rc.Cast(key); err != nil {
-		return false, err
-	} else {
-		if v, err := c.Float64(); err != nil {
-			return false, rc.err(err.Error())
-		} else {
-			return rc.Set(key, v-value)
+	return rc.IncrementFloat(key, -value)
+}
+
+func (rc fCache) Decrement(key string, value int64) (bool, error) {
+	return rc.Increment(key, -value)
+}
+
+func (rc fCache) valueCodec() (codec, bool) {
+	return rc.codec, true
+}
+
+func (rc fCache) getRaw(key string) ([]byte, codec, error) {
+	rec, err := rc.read(key)
+	if err != nil || rec == nil {
+		return nil, rc.codec, err
+	}
+	return rec.value, rc.codec, nil
+}
+
+func (rc fCache) getManyRaw(keys []string) (map[string]rawItem, error) {
+	res := make(map[string]rawItem)
+	mutex := sync.Mutex{}
+	err := rc.parallel(keys, func(key string) error {
+		rec, err := rc.read(key)
+		if err != nil || rec == nil {
+			return err
 		}
+
+		mutex.Lock()
+		res[key] = rawItem{data: rec.value, codec: rc.codec}
+		mutex.Unlock()
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
 	}
+	return res, nil
 }
 
-func (rc fCache) Decrement(key string, value int64) (bool, error) {
-	if c, err := rc.Cast(key); err != nil {
-		return false, err
-	} else {
-		if v, err := c.Int64(); err != nil {
-			return false, rc.err(err.Error())
-		} else {
-			return rc.Set(key, v-value)
+// removeIf remove item file if cond returns true for decoded record header and return removed file size
+func (rc fCache) removeIf(file string, cond func(header recordHeader, err error) bool) (bool, uint64, error) {
+	removed := false
+	var size uint64
+	err := rc.lockedFile(file, func() error {
+		info, err := os.Stat(file)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(file)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		header, err := decodeHeader(f)
+		f.Close()
+		if !cond(header, err) {
+			return nil
+		}
+
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		removed = true
+		size = uint64(info.Size())
+		return nil
+	})
+	return removed, size, err
+}
+
+// entries get item files and temp files of cache directory
+func (rc fCache) entries() ([]string, []os.DirEntry, error) {
+	entries, err := os.ReadDir(rc.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, rc.err("%w", err)
+	}
+
+	items := make([]string, 0, len(entries))
+	temps := make([]os.DirEntry, 0)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		if strings.HasPrefix(entry.Name(), strings.TrimSuffix(tempPattern, "*")) {
+			temps = append(temps, entry)
+		} else if itemFileRx.MatchString(entry.Name()) {
+			items = append(items, path.Join(rc.dir, entry.Name()))
 		}
 	}
+	return items, temps, nil
+}
+
+// Sweep remove expired and corrupt item files and abandoned temp files of cache directory
+func (rc fCache) Sweep() (SweepResult, error) {
+	res := SweepResult{}
+	items, temps, err := rc.entries()
+	if err != nil {
+		return res, err
+	}
+
+	for _, entry := range temps {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < tempStaleAfter {
+			continue
+		}
+
+		if err := os.Remove(path.Join(rc.dir, entry.Name())); err == nil {
+			res.Items++
+			res.Bytes += uint64(info.Size())
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, file := range items {
+		if err := rc.canceled(); err != nil {
+			return res, err
+		}
+
+		removed, size, err := rc.removeIf(file, func(header recordHeader, err error) bool {
+			return err != nil || header.TTL.UTC().Before(now)
+		})
+		if err != nil {
+			return res, rc.err("%w", err)
+		}
+
+		if removed {
+			res.Items++
+			res.Bytes += size
+		}
+	}
+	return res, nil
+}
+
+func (rc fCache) Keys(pattern string) ([]string, error) {
+	return collectKeys(rc, pattern)
+}
+
+// Scan read item files headers, items written by older versions without key info are skipped
+func (rc fCache) Scan(pattern string, fn func(key string) bool) error {
+	items, _, err := rc.entries()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, file := range items {
+		if err := rc.canceled(); err != nil {
+			return err
+		}
+
+		header, err := rc.readHeaderFile(file)
+		if err != nil ||
+			header == nil ||
+			header.Key == "" ||
+			header.Prefix != rc.prefix ||
+			header.TTL.UTC().Before(now) ||
+			!matchGlob(pattern, header.Key) {
+			continue
+		}
+
+		if !fn(header.Key) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (rc fCache) Flush() error {
+	items, _, err := rc.entries()
+	if err != nil {
+		return err
+	}
+
+	return rc.parallel(items, func(file string) error {
+		if _, _, err := rc.removeIf(file, func(header recordHeader, err error) bool {
+			return err == nil && header.Prefix == rc.prefix
+		}); err != nil {
+			return rc.err("%w", err)
+		}
+		return nil
+	})
+}
+
+func (rc fCache) WithContext(ctx context.Context) Cache {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rc.ctx = ctx
+	return &rc
 }
@@ -1,8 +1,18 @@
 package cache_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -124,13 +134,46 @@ func TestFileCacheTTL(t *testing.T) {
 		t.Fail()
 	}
 
-	ttl, err = redisCache().TTL("non-exists")
+	err = fileCache().PutForever("forever", "kim")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if ttl > 0 {
-		t.Fatal("failed non exists ttl")
+	ttl, err = fileCache().TTL("forever")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl != cache.NoExpiration {
+		t.Fatalf("failed forever ttl %v", ttl)
+	}
+
+	_, err = fileCache().TTL("non-exists")
+	if !errors.Is(err, cache.ErrNotFound) {
+		t.Fatal("failed non exists ttl", err)
+	}
+}
+
+func TestFileCacheTouch(t *testing.T) {
+	c := fileCache()
+	err := c.Put("sliding", "data", 3*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Second)
+	ok, err := c.Touch("sliding")
+	if err != nil || !ok {
+		t.Fatal("failed touch", err)
+	}
+
+	ttl, err := c.TTL("sliding")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl <= 2500*time.Millisecond {
+		t.Fatalf("failed reset ttl %v", ttl)
 	}
 }
 
@@ -220,9 +263,402 @@ func TestFileCacheIncDec(t *testing.T) {
 	}
 }
 
+func TestFileCacheWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := fileCache().WithContext(ctx)
+
+	err := c.Put("ctx-val", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if _, err = c.Get("ctx-val"); err == nil {
+		t.Fatal("failed canceled context!")
+	}
+
+	v, err := fileCache().Get("ctx-val")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "kim" {
+		t.Fatalf("failed context free get %s", v)
+	}
+}
+
+func TestFileCacheMany(t *testing.T) {
+	err := fileCache().PutMany(map[string]any{"many-a": "a", "many-b": "b"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := fileCache().GetMany([]string{"many-a", "many-b", "many-c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 || res["many-a"] != "a" || res["many-b"] != "b" {
+		t.Fatalf("failed get many %v", res)
+	}
+
+	if _, ok := res["many-c"]; ok {
+		t.Fatal("failed get many miss")
+	}
+
+	err = fileCache().ForgetMany("many-a", "many-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = fileCache().GetMany([]string{"many-a", "many-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 0 {
+		t.Fatal("failed forget many")
+	}
+}
+
+func TestFileCacheConcurrency(t *testing.T) {
+	err := fileCache().Put("counter", 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// separate driver instance per worker share only cache directory
+			if _, err := fileCache().Increment("counter", 1); err != nil {
+				t.Error(err)
+			}
+			if _, err := fileCache().Get("counter"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	v, err := fileCache().Get("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(v) != "50" {
+		t.Fatalf("failed concurrent increment %v", v)
+	}
+
+	exists, err := fileCache().Exists("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists {
+		t.Fatal("failed exists check!")
+	}
+}
+
+func TestFileCacheSweep(t *testing.T) {
+	dir := t.TempDir()
+	c := cache.NewFileCache("sweep", dir)
+	err := c.Put("expired", "kim", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Put("alive", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path.Join(dir, "0123456789abcdef0123456789abcdef"), []byte("corrupt"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	res, err := c.(cache.Sweeper).Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Items != 2 || res.Bytes == 0 {
+		t.Fatalf("failed sweep %+v", res)
+	}
+
+	v, err := c.Get("alive")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "kim" {
+		t.Fatal("sweep removed alive item")
+	}
+}
+
+func TestFileCacheSweepInterval(t *testing.T) {
+	dir := t.TempDir()
+	c := cache.NewFileCache("sweep", dir, cache.WithSweepInterval(10*time.Millisecond))
+	defer c.(cache.Closer).Close()
+
+	err := c.Put("expired", "kim", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			t.Fatalf("failed background sweep %s", entry.Name())
+		}
+	}
+}
+
+func TestFileCacheClose(t *testing.T) {
+	c := cache.NewFileCache("close", t.TempDir(), cache.WithSweepInterval(10*time.Millisecond))
+	if err := c.WithContext(context.Background()).(cache.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.(cache.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	err := c.Put("expired", "kim", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// expired item kept until sweep when background sweep stopped
+	time.Sleep(50 * time.Millisecond)
+	res, err := c.(cache.Sweeper).Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Items != 1 {
+		t.Fatalf("background sweep not stopped %+v", res)
+	}
+}
+
+func TestFileCacheFlush(t *testing.T) {
+	dir := t.TempDir()
+	mine := cache.NewFileCache("flush-mine", dir)
+	other := cache.NewFileCache("flush-other", dir)
+	for _, c := range []cache.Cache{mine, other} {
+		if err := c.Put("flush", "kim", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := mine.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := mine.Exists("flush")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Fatal("failed flush!")
+	}
+
+	exists, err = other.Exists("flush")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists {
+		t.Fatal("flush removed other prefix item")
+	}
+}
+
+func TestFileCacheKeys(t *testing.T) {
+	c := cache.NewFileCache("keys", t.TempDir())
+	err := c.PutMany(map[string]any{"user:1": 1, "user:2": 2, "post:1": 1}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := c.Keys("user:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "user:1,user:2" {
+		t.Fatalf("failed keys %v", keys)
+	}
+
+	count := 0
+	err = c.Scan("*", func(key string) bool {
+		count++
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Fatal("failed scan stop")
+	}
+}
+
+func TestFileCacheFormat(t *testing.T) {
+	dir := t.TempDir()
+	c := cache.NewFileCache("format", dir)
+	err := c.Put("name", "john", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := func(key string) string {
+		sum := md5.Sum([]byte("format-" + key))
+		return path.Join(dir, hex.EncodeToString(sum[:]))
+	}
+
+	content, err := os.ReadFile(file("name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(content, []byte("BCF")) || !bytes.Contains(content, []byte("name")) {
+		t.Fatalf("failed binary format %q", content)
+	}
+
+	// record of older versions stored as hex encoded gob
+	legacy := struct {
+		TTL    time.Time
+		Prefix string
+		Key    string
+		Data   any
+	}{time.Now().Add(time.Minute), "format", "legacy", "old"}
+	b := bytes.Buffer{}
+	if err := gob.NewEncoder(&b).Encode(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(file("legacy"), []byte(hex.EncodeToString(b.Bytes())), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := c.Get("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "old" {
+		t.Fatalf("failed read legacy record %v", v)
+	}
+
+	ttl, err := c.TTL("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("failed legacy ttl %v", ttl)
+	}
+
+	keys, err := c.Keys("*")
+	sort.Strings(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(keys, ",") != "legacy,name" {
+		t.Fatalf("failed keys %v", keys)
+	}
+
+	// binary record of format version 1 without lifetime
+	v1 := append([]byte("BCF\x01"), make([]byte, 8)...)
+	v1 = append(v1, 6)
+	v1 = append(v1, "format"...)
+	v1 = append(v1, 2)
+	v1 = append(v1, "v1"...)
+	v1 = append(v1, 0)
+	v1 = append(v1, "42"...)
+	err = os.WriteFile(file("v1"), v1, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err = c.Get("v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != int64(42) {
+		t.Fatalf("failed read version 1 record %v", v)
+	}
+
+	ok, err := c.Set("legacy", "new")
+	if err != nil || !ok {
+		t.Fatal("failed update legacy record", err)
+	}
+
+	content, err = os.ReadFile(file("legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(content, []byte("BCF")) {
+		t.Fatal("legacy record not migrated on write")
+	}
+}
+
 func TestCleanup(t *testing.T) {
 	err := os.RemoveAll("./caches")
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestFileCacheNotNumeric(t *testing.T) {
+	c := cache.NewFileCache("mine", t.TempDir())
+	err := c.Put("not-numeric", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Increment("not-numeric", 1)
+	if !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed increment non numeric", err)
+	}
+
+	_, err = c.DecrementFloat("not-numeric", 0.5)
+	if !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed decrement float non numeric", err)
+	}
+
+	err = c.Put("float-item", 1.5, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Increment("float-item", 1)
+	if !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed increment float item", err)
+	}
+
+	v, err := c.Get("not-numeric")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "kim" {
+		t.Fatalf("failed non numeric value changed %v", v)
+	}
+}
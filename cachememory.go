@@ -0,0 +1,611 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bopher/caster"
+	"github.com/bopher/utils"
+)
+
+// memory cache item, expiration and ttl zero value means item never expires
+type mItem struct {
+	key        string
+	data       []byte
+	size       uint64
+	expiration time.Time
+	ttl        time.Duration
+}
+
+func (it mItem) isExpired(now time.Time) bool {
+	return !it.expiration.IsZero() && it.expiration.Before(now)
+}
+
+// memory store shared between memory cache driver and its context copies
+type mStore struct {
+	mutex      sync.Mutex
+	maxEntries uint
+	maxBytes   uint64
+	size       uint64
+	items      map[string]*list.Element
+	order      *list.List
+	stopper    *stopper
+}
+
+func (ms *mStore) remove(el *list.Element) {
+	it := el.Value.(*mItem)
+	ms.order.Remove(el)
+	delete(ms.items, it.key)
+	ms.size -= it.size
+}
+
+// lookup return non-expired item element and remove expired one, must called with lock
+func (ms *mStore) lookup(key string) *list.Element {
+	el, ok := ms.items[key]
+	if !ok {
+		return nil
+	}
+
+	if el.Value.(*mItem).isExpired(time.Now()) {
+		ms.remove(el)
+		return nil
+	}
+	return el
+}
+
+// store set item as most recently used and evict least recently used items, must called with lock
+func (ms *mStore) store(key string, data []byte, expiration time.Time, ttl time.Duration) {
+	if el, ok := ms.items[key]; ok {
+		ms.remove(el)
+	}
+
+	it := &mItem{
+		key:        key,
+		data:       data,
+		size:       uint64(len(key) + len(data)),
+		expiration: expiration,
+		ttl:        ttl,
+	}
+	ms.items[key] = ms.order.PushFront(it)
+	ms.size += it.size
+
+	for ms.order.Len() > 0 &&
+		((ms.maxEntries > 0 && uint(ms.order.Len()) > ms.maxEntries) ||
+			(ms.maxBytes > 0 && ms.size > ms.maxBytes)) {
+		ms.remove(ms.order.Back())
+	}
+}
+
+// update change item data and keep item ttl, must called with lock
+func (ms *mStore) update(el *list.Element, data []byte) {
+	it := el.Value.(*mItem)
+	ms.store(it.key, data, it.expiration, it.ttl)
+}
+
+func (ms *mStore) deleteExpired() SweepResult {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	res := SweepResult{}
+	now := time.Now()
+	for el := ms.order.Back(); el != nil; {
+		prev := el.Prev()
+		if it := el.Value.(*mItem); it.isExpired(now) {
+			res.Items++
+			res.Bytes += it.size
+			ms.remove(el)
+		}
+		el = prev
+	}
+	return res
+}
+
+func (ms *mStore) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ms.deleteExpired()
+		case <-ms.stopper.stop:
+			return
+		}
+	}
+}
+
+type mCache struct {
+	store *mStore
+	codec codec
+	ctx   context.Context
+}
+
+func (mc mCache) err(pattern string, params ...any) error {
+	return utils.TaggedError([]string{"MemoryCache"}, pattern, params...)
+}
+
+func (mc *mCache) init(maxEntries uint, maxBytes uint64, cleanupInterval time.Duration, conf config) {
+	mc.store = &mStore{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+	}
+	mc.codec = newCodec(conf)
+	mc.ctx = context.Background()
+
+	if cleanupInterval > 0 {
+		mc.store.stopper = newStopper()
+		go mc.store.janitor(cleanupInterval)
+	}
+}
+
+// Close stop background cleanup of cache and its context copies
+func (mc mCache) Close() error {
+	mc.store.stopper.close()
+	return nil
+}
+
+func (mc mCache) canceled() error {
+	if err := mc.ctx.Err(); err != nil {
+		return mc.err("%w", err)
+	}
+	return nil
+}
+
+func (mc mCache) encode(value any) ([]byte, error) {
+	if encoded, err := mc.codec.encode(value); err != nil {
+		return nil, mc.err("%w", err)
+	} else {
+		return encoded, nil
+	}
+}
+
+func (mc mCache) decode(data []byte) (any, error) {
+	if v, err := mc.codec.decode(data); err != nil {
+		return nil, mc.err("%w", err)
+	} else {
+		return v, nil
+	}
+}
+
+func (mc mCache) put(key string, value any, expiration time.Time, ttl time.Duration) error {
+	if err := mc.canceled(); err != nil {
+		return err
+	}
+
+	encoded, err := mc.encode(value)
+	if err != nil {
+		return err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	mc.store.store(key, encoded, expiration, ttl)
+	return nil
+}
+
+// expiration get expiration of item with ttl, zero ttl and expiration returned for non-positive ttl (never expires)
+func expiration(ttl time.Duration) (time.Time, time.Duration) {
+	if ttl <= 0 {
+		return time.Time{}, 0
+	}
+	return time.Now().Add(ttl), ttl
+}
+
+func (mc mCache) Put(key string, value any, ttl time.Duration) error {
+	expiration, ttl := expiration(ttl)
+	return mc.put(key, value, expiration, ttl)
+}
+
+func (mc mCache) PutForever(key string, value any) error {
+	return mc.put(key, value, time.Time{}, 0)
+}
+
+func (mc mCache) PutMany(values map[string]any, ttl time.Duration) error {
+	if err := mc.canceled(); err != nil {
+		return err
+	}
+
+	encoded := make(map[string][]byte, len(values))
+	for k, v := range values {
+		if e, err := mc.encode(v); err != nil {
+			return err
+		} else {
+			encoded[k] = e
+		}
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	expiration, ttl := expiration(ttl)
+	for k, v := range encoded {
+		mc.store.store(k, v, expiration, ttl)
+	}
+	return nil
+}
+
+func (mc mCache) Set(key string, value any) (bool, error) {
+	if err := mc.canceled(); err != nil {
+		return false, err
+	}
+
+	encoded, err := mc.encode(value)
+	if err != nil {
+		return false, err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	el := mc.store.lookup(key)
+	if el == nil {
+		return false, nil
+	}
+
+	mc.store.update(el, encoded)
+	return true, nil
+}
+
+func (mc mCache) Add(key string, value any, ttl time.Duration) (bool, error) {
+	if err := mc.canceled(); err != nil {
+		return false, err
+	}
+
+	encoded, err := mc.encode(value)
+	if err != nil {
+		return false, err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	if mc.store.lookup(key) != nil {
+		return false, nil
+	}
+
+	expiration, ttl := expiration(ttl)
+	mc.store.store(key, encoded, expiration, ttl)
+	return true, nil
+}
+
+func (mc mCache) CompareAndSwap(key string, old any, new any) (bool, error) {
+	if err := mc.canceled(); err != nil {
+		return false, err
+	}
+
+	encodedOld, err := mc.encode(old)
+	if err != nil {
+		return false, err
+	}
+
+	encodedNew, err := mc.encode(new)
+	if err != nil {
+		return false, err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	el := mc.store.lookup(key)
+	if el == nil || !matchStored(el.Value.(*mItem).data, encodedOld, old) {
+		return false, nil
+	}
+
+	mc.store.update(el, encodedNew)
+	return true, nil
+}
+
+func (mc mCache) Get(key string) (any, error) {
+	if err := mc.canceled(); err != nil {
+		return nil, err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	el := mc.store.lookup(key)
+	if el == nil {
+		return nil, nil
+	}
+
+	mc.store.order.MoveToFront(el)
+	return mc.decode(el.Value.(*mItem).data)
+}
+
+func (mc mCache) GetMany(keys []string) (map[string]any, error) {
+	raws, err := mc.getManyRaw(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]any, len(raws))
+	for k, raw := range raws {
+		if res[k], err = mc.decode(raw.data); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (mc mCache) Exists(key string) (bool, error) {
+	if err := mc.canceled(); err != nil {
+		return false, err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	return mc.store.lookup(key) != nil, nil
+}
+
+func (mc mCache) Forget(key string) error {
+	if err := mc.canceled(); err != nil {
+		return err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	if el, ok := mc.store.items[key]; ok {
+		mc.store.remove(el)
+	}
+	return nil
+}
+
+func (mc mCache) ForgetMany(keys ...string) error {
+	if err := mc.canceled(); err != nil {
+		return err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	for _, k := range keys {
+		if el, ok := mc.store.items[k]; ok {
+			mc.store.remove(el)
+		}
+	}
+	return nil
+}
+
+func (mc mCache) Keys(pattern string) ([]string, error) {
+	return collectKeys(mc, pattern)
+}
+
+func (mc mCache) Scan(pattern string, fn func(key string) bool) error {
+	if err := mc.canceled(); err != nil {
+		return err
+	}
+
+	// collect keys before calling fn, so fn can use cache
+	mc.store.mutex.Lock()
+	keys := make([]string, 0)
+	now := time.Now()
+	for key, el := range mc.store.items {
+		if !el.Value.(*mItem).isExpired(now) && matchGlob(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	mc.store.mutex.Unlock()
+
+	for _, key := range keys {
+		if !fn(key) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (mc mCache) Flush() error {
+	if err := mc.canceled(); err != nil {
+		return err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	mc.store.items = make(map[string]*list.Element)
+	mc.store.order.Init()
+	mc.store.size = 0
+	return nil
+}
+
+func (mc mCache) Pull(key string) (any, error) {
+	if err := mc.canceled(); err != nil {
+		return nil, err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	el := mc.store.lookup(key)
+	if el == nil {
+		return nil, nil
+	}
+
+	mc.store.remove(el)
+	return mc.decode(el.Value.(*mItem).data)
+}
+
+func (mc mCache) TTL(key string) (time.Duration, error) {
+	if err := mc.canceled(); err != nil {
+		return 0, err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	el := mc.store.lookup(key)
+	if el == nil {
+		return 0, mc.err("%s: %w", key, ErrNotFound)
+	}
+
+	it := el.Value.(*mItem)
+	if it.expiration.IsZero() {
+		return NoExpiration, nil
+	}
+	return time.Until(it.expiration), nil
+}
+
+// expire change item expiration with fn and remove item if fn returns false, return false if item not exists
+func (mc mCache) expire(key string, fn func(it *mItem) bool) (bool, error) {
+	if err := mc.canceled(); err != nil {
+		return false, err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	el := mc.store.lookup(key)
+	if el == nil {
+		return false, nil
+	}
+
+	if !fn(el.Value.(*mItem)) {
+		mc.store.remove(el)
+	}
+	return true, nil
+}
+
+func (mc mCache) Expire(key string, ttl time.Duration) (bool, error) {
+	return mc.expire(key, func(it *mItem) bool {
+		it.expiration = time.Now().Add(ttl)
+		it.ttl = ttl
+		return ttl > 0
+	})
+}
+
+func (mc mCache) Touch(key string) (bool, error) {
+	return mc.expire(key, func(it *mItem) bool {
+		if it.ttl > 0 {
+			it.expiration = time.Now().Add(it.ttl)
+		}
+		return true
+	})
+}
+
+func (mc mCache) Persist(key string) (bool, error) {
+	return mc.expire(key, func(it *mItem) bool {
+		it.expiration = time.Time{}
+		it.ttl = 0
+		return true
+	})
+}
+
+func (mc mCache) Cast(key string) (caster.Caster, error) {
+	v, err := mc.Get(key)
+	return caster.NewCaster(v), err
+}
+
+// incr add delta to numeric item atomically, float delta used when isFloat is true
+func (mc mCache) incr(key string, delta int64, fDelta float64, isFloat bool) (bool, error) {
+	if err := mc.canceled(); err != nil {
+		return false, err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	el := mc.store.lookup(key)
+	if el == nil {
+		return false, nil
+	}
+
+	// serialized values never numeric
+	current, _ := numberOf(el.Value.(*mItem).data)
+	var value any
+	if isFloat {
+		v, ok := toFloat64(current)
+		if !ok {
+			return false, mc.err("%s: %w", key, ErrNotNumeric)
+		}
+		value = v + fDelta
+	} else {
+		v, ok := toInt64(current)
+		if !ok {
+			return false, mc.err("%s: %w", key, ErrNotNumeric)
+		}
+		value = v + delta
+	}
+
+	encoded, err := mc.encode(value)
+	if err != nil {
+		return false, err
+	}
+	mc.store.update(el, encoded)
+	return true, nil
+}
+
+func (mc mCache) IncrementFloat(key string, value float64) (bool, error) {
+	return mc.incr(key, 0, value, true)
+}
+
+func (mc mCache) Increment(key string, value int64) (bool, error) {
+	return mc.incr(key, value, 0, false)
+}
+
+func (mc mCache) DecrementFloat(key string, value float64) (bool, error) {
+	return mc.incr(key, 0, -value, true)
+}
+
+func (mc mCache) Decrement(key string, value int64) (bool, error) {
+	return mc.incr(key, -value, 0, false)
+}
+
+func (mc mCache) Sweep() (SweepResult, error) {
+	if err := mc.canceled(); err != nil {
+		return SweepResult{}, err
+	}
+	return mc.store.deleteExpired(), nil
+}
+
+func (mc mCache) valueCodec() (codec, bool) {
+	return mc.codec, true
+}
+
+func (mc mCache) getRaw(key string) ([]byte, codec, error) {
+	if err := mc.canceled(); err != nil {
+		return nil, mc.codec, err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	el := mc.store.lookup(key)
+	if el == nil {
+		return nil, mc.codec, nil
+	}
+
+	mc.store.order.MoveToFront(el)
+	return el.Value.(*mItem).data, mc.codec, nil
+}
+
+func (mc mCache) getManyRaw(keys []string) (map[string]rawItem, error) {
+	if err := mc.canceled(); err != nil {
+		return nil, err
+	}
+
+	mc.store.mutex.Lock()
+	defer mc.store.mutex.Unlock()
+
+	res := make(map[string]rawItem)
+	for _, k := range keys {
+		if el := mc.store.lookup(k); el != nil {
+			mc.store.order.MoveToFront(el)
+			res[k] = rawItem{data: el.Value.(*mItem).data, codec: mc.codec}
+		}
+	}
+	return res, nil
+}
+
+func (mc mCache) WithContext(ctx context.Context) Cache {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	mc.ctx = ctx
+	return &mc
+}
@@ -0,0 +1,519 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bopher/cache"
+)
+
+var memCache = cache.NewMemoryCache(100, 0, time.Minute)
+
+func memoryCache() cache.Cache {
+	return memCache
+}
+
+func TestMemoryCachePut(t *testing.T) {
+	err := memoryCache().Put("name", "kim", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := memoryCache().Get("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "kim" {
+		t.Fatalf("failed put %s", v)
+	}
+}
+
+func TestMemoryCacheSet(t *testing.T) {
+	exists, err := memoryCache().Set("non-exists", "Bla")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Fatalf(`failed exists check!`)
+	}
+
+	err = memoryCache().Put("name", "John", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err = memoryCache().Set("name", "Kate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists {
+		t.Fatalf(`failed exists check!`)
+	}
+
+	v, err := memoryCache().Get("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "Kate" {
+		t.Fatalf(`Want "Kate" get %s`, v)
+	}
+}
+
+func TestMemoryCacheForget(t *testing.T) {
+	err := memoryCache().Put("name", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = memoryCache().Forget("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := memoryCache().Get("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != nil {
+		t.Fatal("failed forget!")
+	}
+}
+
+func TestMemoryCachePull(t *testing.T) {
+	err := memoryCache().Put("name", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := memoryCache().Pull("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v == nil {
+		t.Fatal("failed pull get!")
+	}
+
+	v, err = memoryCache().Get("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != nil {
+		t.Fatal("failed pull forget!")
+	}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	err := memoryCache().Put("name", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ttl, err := memoryCache().TTL("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl < 59*time.Second {
+		t.Fail()
+	}
+
+	err = memoryCache().PutForever("forever", "kim")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ttl, err = memoryCache().TTL("forever")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl != cache.NoExpiration {
+		t.Fatalf("failed forever ttl %v", ttl)
+	}
+
+	_, err = memoryCache().TTL("non-exists")
+	if !errors.Is(err, cache.ErrNotFound) {
+		t.Fatal("failed non exists ttl", err)
+	}
+}
+
+func TestMemoryCacheTouch(t *testing.T) {
+	c := memoryCache()
+	err := c.Put("sliding", "data", 3*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Second)
+	ok, err := c.Touch("sliding")
+	if err != nil || !ok {
+		t.Fatal("failed touch", err)
+	}
+
+	ttl, err := c.TTL("sliding")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl <= 2500*time.Millisecond {
+		t.Fatalf("failed reset ttl %v", ttl)
+	}
+}
+
+func TestMemoryCacheIncDecFloat(t *testing.T) {
+	err := memoryCache().Put("float-val", 10.1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := memoryCache().IncrementFloat("float-val", 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists {
+		t.Fatal("item not exists!")
+	}
+
+	v, err := memoryCache().Get("float-val")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(v) != "10.4" {
+		t.Fatal("failed increment")
+	}
+
+	exists, err = memoryCache().DecrementFloat("float-val", 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists {
+		t.Fatal("item not exists!")
+	}
+
+	v, err = memoryCache().Get("float-val")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(v) != "9.9" {
+		t.Fatal("failed decrement")
+	}
+}
+
+func TestMemoryCacheIncDec(t *testing.T) {
+	err := memoryCache().Put("int-val", 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := memoryCache().Increment("int-val", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists {
+		t.Fatal("item not exists!")
+	}
+
+	v, err := memoryCache().Get("int-val")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(v) != "9" {
+		t.Fatalf("failed increment")
+	}
+
+	exists, err = memoryCache().Decrement("int-val", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists {
+		t.Fatal("item not exists!")
+	}
+
+	v, err = memoryCache().Get("int-val")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(v) != "7" {
+		t.Fatal("failed decrement")
+	}
+}
+
+func TestMemoryCacheMany(t *testing.T) {
+	err := memoryCache().PutMany(map[string]any{"many-a": "a", "many-b": "b"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := memoryCache().GetMany([]string{"many-a", "many-b", "many-c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 || res["many-a"] != "a" || res["many-b"] != "b" {
+		t.Fatalf("failed get many %v", res)
+	}
+
+	if _, ok := res["many-c"]; ok {
+		t.Fatal("failed get many miss")
+	}
+
+	err = memoryCache().ForgetMany("many-a", "many-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = memoryCache().GetMany([]string{"many-a", "many-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 0 {
+		t.Fatal("failed forget many")
+	}
+}
+
+func TestMemoryCacheFlush(t *testing.T) {
+	c := cache.NewMemoryCache(0, 0, 0)
+	err := c.Put("flush", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := c.Exists("flush")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Fatal("failed flush!")
+	}
+}
+
+func TestMemoryCacheKeys(t *testing.T) {
+	c := cache.NewMemoryCache(0, 0, 0)
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	err := c.PutMany(map[string]any{"user:1": 1, "user:2": 2, "post:1": 1}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := c.Keys("user:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "user:1,user:2" {
+		t.Fatalf("failed keys %v", keys)
+	}
+
+	count := 0
+	err = c.Scan("*", func(key string) bool {
+		count++
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Fatal("failed scan stop")
+	}
+}
+
+func TestMemoryCacheExpiration(t *testing.T) {
+	c := cache.NewMemoryCache(0, 0, 10*time.Millisecond)
+	err := c.Put("name", "kim", 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	exists, err := c.Exists("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Fatal("failed expiration!")
+	}
+	c = cache.NewMemoryCache(0, 0, 0)
+	err = c.Put("name", "kim", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	res, err := c.(cache.Sweeper).Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Items != 1 {
+		t.Fatalf("failed sweep %+v", res)
+	}
+}
+
+func TestMemoryCacheClose(t *testing.T) {
+	c := cache.NewMemoryCache(0, 0, 10*time.Millisecond)
+	if err := c.WithContext(context.Background()).(cache.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.(cache.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	err := c.Put("name", "kim", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// expired item kept until sweep when background cleanup stopped
+	time.Sleep(50 * time.Millisecond)
+	res, err := c.(cache.Sweeper).Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Items != 1 {
+		t.Fatalf("background cleanup not stopped %+v", res)
+	}
+
+	if err := cache.NewMemoryCache(0, 0, 0).(cache.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	c := cache.NewMemoryCache(2, 0, 0)
+	for _, k := range []string{"a", "b"} {
+		if err := c.Put(k, k, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// touch a to make b least recently used
+	if _, err := c.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Put("c", "c", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		exists, err := c.Exists(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exists != want {
+			t.Fatalf("failed eviction for %s", k)
+		}
+	}
+
+	c = cache.NewMemoryCache(0, 10, 0)
+	if err := c.Put("big", "0123456789", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := c.Get("big"); v != nil {
+		t.Fatal("failed max bytes eviction")
+	}
+}
+
+func TestMemoryCacheConcurrency(t *testing.T) {
+	c := cache.NewMemoryCache(0, 0, 0)
+	err := c.Put("counter", 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Increment("counter", 1); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	v, err := c.Get("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(v) != "50" {
+		t.Fatalf("failed concurrent increment %v", v)
+	}
+}
+
+func TestMemoryCacheNotNumeric(t *testing.T) {
+	c := memoryCache()
+	err := c.Put("not-numeric", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Increment("not-numeric", 1)
+	if !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed increment non numeric", err)
+	}
+
+	_, err = c.DecrementFloat("not-numeric", 0.5)
+	if !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed decrement float non numeric", err)
+	}
+
+	err = c.Put("float-item", 1.5, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Increment("float-item", 1)
+	if !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed increment float item", err)
+	}
+
+	v, err := c.Get("not-numeric")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "kim" {
+		t.Fatalf("failed non numeric value changed %v", v)
+	}
+}
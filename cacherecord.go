@@ -1,30 +1,190 @@
 package cache
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"time"
 )
 
 // cache record used for working with file cache
+//
+// record file layout:
+//
+//	magic "BCF" | format version (1 byte) | expiry unix nano (int64 big endian, 0 for forever) |
+//	lifetime nano (int64 big endian, 0 for forever) | prefix length (uvarint) | prefix |
+//	key length (uvarint) | key | codec id (1 byte) | payload
+//
+// lifetime is ttl item put with and not exists in format version 1.
+// codec id is serializer id of serialized values or rawCodec for numeric and compressed values,
+// numbers stored without number marker by older versions marked on read.
+// files of older versions are hex encoded gob of recordFile and still readable.
+
+const (
+	recordMagic          = "BCF"
+	recordVersion   byte = 2
+	rawCodec        byte = 0
+	maxHeaderString      = 1 << 16
+)
+
+var errInvalidRecord = errors.New("invalid record file")
+
+// expiry of records without ttl, latest time representable as unix nano
+var foreverTTL = time.Unix(0, math.MaxInt64)
 
 type record struct {
-	TTL  time.Time
-	Data any
+	TTL      time.Time
+	Lifetime time.Duration
+	Prefix   string
+	Key      string
+	Data     any
+	// encoded data of deserialized record
+	value []byte
 }
 
-func (rc record) Serialize() (string, error) {
-	b := bytes.Buffer{}
-	e := gob.NewEncoder(&b)
-	err := e.Encode(rc)
+// stored form of records of older versions, Data field only used by oldest records
+type recordFile struct {
+	TTL    time.Time
+	Prefix string
+	Key    string
+	Data   any
+	Value  []byte
+}
+
+// record header used for decoding record meta without decoding data
+type recordHeader struct {
+	TTL      time.Time
+	Lifetime time.Duration
+	Prefix   string
+	Key      string
+	Codec    byte
+}
+
+// headerReader source of record header
+type headerReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+func (rc record) Serialize(c codec) ([]byte, error) {
+	value, err := c.encode(rc.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	header := recordHeader{
+		TTL:      rc.TTL,
+		Lifetime: rc.Lifetime,
+		Prefix:   rc.Prefix,
+		Key:      rc.Key,
+	}
+	header.Codec, value = splitValue(value)
+	return header.encode(value), nil
+}
+
+func (rc *record) Deserialize(c codec, data []byte) error {
+	if !bytes.HasPrefix(data, []byte(recordMagic)) {
+		return rc.deserializeLegacy(c, string(data))
+	}
+
+	header, payload, err := splitRecord(c, data)
+	if err != nil {
+		return err
+	}
+
+	rc.TTL = header.TTL
+	rc.Lifetime = header.Lifetime
+	rc.Prefix = header.Prefix
+	rc.Key = header.Key
+	rc.value = markNumber(payload)
+	if header.Codec != rawCodec {
+		rc.value = append([]byte{valueMarker, header.Codec}, payload...)
+	}
+	rc.Data, err = c.decode(rc.value)
+	return err
+}
+
+// splitValue get codec id and payload of encoded value
+func splitValue(value []byte) (byte, []byte) {
+	if len(value) >= 2 && value[0] == valueMarker {
+		return value[1], value[2:]
+	}
+	return rawCodec, value
+}
+
+// markNumber mark plain numeric payload stored by older versions, other payloads returned as is
+func markNumber(payload []byte) []byte {
+	if len(payload) == 0 || payload[0] <= floatMarker {
+		return payload
+	}
+
+	n, ok := parseNumber(string(payload))
+	if !ok {
+		return payload
+	}
+
+	if _, isFloat := n.(float64); isFloat {
+		return append([]byte{floatMarker}, payload...)
+	}
+	return append([]byte{intMarker}, payload...)
+}
+
+// splitRecord get header and payload of record file content without decoding payload,
+// records of older versions decoded and converted to current format
+func splitRecord(c codec, data []byte) (recordHeader, []byte, error) {
+	if !bytes.HasPrefix(data, []byte(recordMagic)) {
+		rc := record{}
+		if err := rc.deserializeLegacy(c, string(data)); err != nil {
+			return recordHeader{}, nil, err
+		}
+
+		header := recordHeader{TTL: rc.TTL, Prefix: rc.Prefix, Key: rc.Key}
+		codec, payload := splitValue(rc.value)
+		header.Codec = codec
+		return header, payload, nil
+	}
+
+	r := bytes.NewReader(data)
+	header, err := readHeader(r)
 	if err != nil {
-		return "", err
+		return header, nil, err
 	}
-	return hex.EncodeToString(b.Bytes()), nil
+	return header, data[len(data)-r.Len():], nil
 }
 
-func (rc *record) Deserialize(data string) error {
+// encode get record file content of header and payload
+func (rh recordHeader) encode(payload []byte) []byte {
+	expiry := int64(0)
+	if rh.TTL.Before(foreverTTL) {
+		expiry = rh.TTL.UnixNano()
+	}
+
+	b := bytes.Buffer{}
+	b.Grow(len(recordMagic) + 1 + 16 + 2*binary.MaxVarintLen64 + len(rh.Prefix) + len(rh.Key) + 1 + len(payload))
+	b.WriteString(recordMagic)
+	b.WriteByte(recordVersion)
+	num := make([]byte, binary.MaxVarintLen64)
+	binary.BigEndian.PutUint64(num, uint64(expiry))
+	b.Write(num[:8])
+	binary.BigEndian.PutUint64(num, uint64(rh.Lifetime))
+	b.Write(num[:8])
+	b.Write(num[:binary.PutUvarint(num, uint64(len(rh.Prefix)))])
+	b.WriteString(rh.Prefix)
+	b.Write(num[:binary.PutUvarint(num, uint64(len(rh.Key)))])
+	b.WriteString(rh.Key)
+	b.WriteByte(rh.Codec)
+	b.Write(payload)
+	return b.Bytes()
+}
+
+// deserializeLegacy decode hex encoded gob record of older versions
+func (rc *record) deserializeLegacy(c codec, data string) error {
 	by, err := hex.DecodeString(data)
 	if err != nil {
 		return err
@@ -32,13 +192,111 @@ func (rc *record) Deserialize(data string) error {
 	b := bytes.Buffer{}
 	b.Write(by)
 	d := gob.NewDecoder(&b)
-	err = d.Decode(rc)
+	file := recordFile{}
+	err = d.Decode(&file)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	rc.TTL = file.TTL
+	rc.Prefix = file.Prefix
+	rc.Key = file.Key
+	rc.Data = file.Data
+	rc.value = file.Value
+	if file.Value == nil {
+		rc.value, err = c.encode(file.Data)
+	} else {
+		rc.Data, err = c.decode(file.Value)
+	}
+	return err
+}
+
+// decodeHeader decode only meta of record file content
+func decodeHeader(r io.Reader) (recordHeader, error) {
+	br := bufio.NewReaderSize(r, 512)
+	if magic, err := br.Peek(len(recordMagic)); err != nil || string(magic) != recordMagic {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return recordHeader{}, err
+		}
+		return decodeLegacyHeader(string(data))
+	}
+	return readHeader(br)
+}
+
+// readHeader read binary record header, r left at start of payload
+func readHeader(r headerReader) (recordHeader, error) {
+	header := recordHeader{}
+	fixed := make([]byte, len(recordMagic)+1+8)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return header, errInvalidRecord
+	}
+
+	if string(fixed[:len(recordMagic)]) != recordMagic {
+		return header, errInvalidRecord
+	}
+
+	version := fixed[len(recordMagic)]
+	if version < 1 || version > recordVersion {
+		return header, fmt.Errorf("unsupported record format version %d", version)
+	}
+
+	header.TTL = foreverTTL
+	if expiry := int64(binary.BigEndian.Uint64(fixed[len(recordMagic)+1:])); expiry != 0 {
+		header.TTL = time.Unix(0, expiry)
+	}
+
+	if version >= 2 {
+		lifetime := make([]byte, 8)
+		if _, err := io.ReadFull(r, lifetime); err != nil {
+			return header, errInvalidRecord
+		}
+		header.Lifetime = time.Duration(binary.BigEndian.Uint64(lifetime))
+	}
+
+	var err error
+	if header.Prefix, err = readHeaderString(r); err != nil {
+		return header, err
+	}
+
+	if header.Key, err = readHeaderString(r); err != nil {
+		return header, err
+	}
+
+	if header.Codec, err = r.ReadByte(); err != nil {
+		return header, errInvalidRecord
+	}
+	return header, nil
+}
+
+func readHeaderString(r headerReader) (string, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil || size > maxHeaderString {
+		return "", errInvalidRecord
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", errInvalidRecord
+	}
+	return string(b), nil
+}
+
+// decodeLegacyHeader decode only meta of hex encoded gob record of older versions
+func decodeLegacyHeader(data string) (recordHeader, error) {
+	header := recordHeader{}
+	by, err := hex.DecodeString(data)
+	if err != nil {
+		return header, err
+	}
+	err = gob.NewDecoder(bytes.NewReader(by)).Decode(&header)
+	return header, err
 }
 
 func (rc record) IsExpired() bool {
 	return rc.TTL.UTC().Before(time.Now().UTC())
 }
+
+func (rh recordHeader) IsExpired() bool {
+	return rh.TTL.UTC().Before(time.Now().UTC())
+}
@@ -1,8 +1,12 @@
 package cache
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bopher/caster"
@@ -10,91 +14,376 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
+// luaValues lua helpers of stored values. split split lifetime header and payload of value, lifetime get lifetime
+// of header, withLifetime add lifetime header to payload, number parse marked and plain numbers and marked format integer for storing
+var luaValues = fmt.Sprintf(`
+local int_marker = string.char(%d)
+local float_marker = string.char(%d)
+local lifetime_marker = string.char(%d)
+local function split(v)
+	if v and string.sub(v, 1, 1) == lifetime_marker then
+		local pos = string.find(v, ":", 2, true)
+		if pos then
+			return string.sub(v, 1, pos), string.sub(v, pos + 1)
+		end
+	end
+	return "", v
+end
+local function lifetime(header)
+	return tonumber(string.sub(header, 2, -2))
+end
+local function with_lifetime(ttl, payload)
+	return lifetime_marker .. ttl .. ":" .. payload
+end
+local function number(v)
+	local _, payload = split(v)
+	if payload and (string.sub(payload, 1, 1) == int_marker or string.sub(payload, 1, 1) == float_marker) then
+		payload = string.sub(payload, 2)
+	end
+	return tonumber(payload)
+end
+local function marked(n)
+	return int_marker .. string.format("%%d", n)
+end
+`, intMarker, floatMarker, lifetimeMarker)
+
+// scripts run existence or value check and mutation atomically, return 0 if check failed
+// and -1 if numeric operation run on non numeric value. scripts keep lifetime header of values,
+// numeric scripts remove number marker before changing value with redis commands and mark result
+var (
+	setScript = redis.NewScript(luaValues + `
+local v = redis.call("GET", KEYS[1])
+if not v then
+	return 0
+end
+local header = split(v)
+redis.call("SET", KEYS[1], header .. ARGV[1], "KEEPTTL")
+return 1`)
+	incrScript = redis.NewScript(luaValues + `
+local v = redis.call("GET", KEYS[1])
+if not v then
+	return 0
+end
+local header, payload = split(v)
+if string.sub(payload, 1, 1) == int_marker then
+	payload = string.sub(payload, 2)
+end
+redis.call("SET", KEYS[1], payload, "KEEPTTL")
+local res = redis.pcall("INCRBY", KEYS[1], ARGV[1])
+if type(res) ~= "number" then
+	redis.call("SET", KEYS[1], v, "KEEPTTL")
+	return -1
+end
+redis.call("SET", KEYS[1], header .. int_marker .. redis.call("GET", KEYS[1]), "KEEPTTL")
+return 1`)
+	casScript = redis.NewScript(luaValues + `
+local v = redis.call("GET", KEYS[1])
+if not v then
+	return 0
+end
+local header, payload = split(v)
+if payload ~= ARGV[1] and payload ~= ARGV[3] then
+	return 0
+end
+redis.call("SET", KEYS[1], header .. ARGV[2], "KEEPTTL")
+return 1`)
+	incrFloatScript = redis.NewScript(luaValues + `
+local v = redis.call("GET", KEYS[1])
+if not v then
+	return 0
+end
+local header, payload = split(v)
+local marker = string.sub(payload, 1, 1)
+if marker == int_marker or marker == float_marker then
+	payload = string.sub(payload, 2)
+end
+redis.call("SET", KEYS[1], payload, "KEEPTTL")
+local res = redis.pcall("INCRBYFLOAT", KEYS[1], ARGV[1])
+if type(res) ~= "string" then
+	redis.call("SET", KEYS[1], v, "KEEPTTL")
+	return -1
+end
+redis.call("SET", KEYS[1], header .. float_marker .. res, "KEEPTTL")
+return 1`)
+	addScript = redis.NewScript(`
+local ttl = tonumber(ARGV[2])
+local ok
+if ttl > 0 then
+	ok = redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ttl)
+else
+	ok = redis.call("SET", KEYS[1], ARGV[1], "NX")
+end
+if not ok then
+	return 0
+end
+return 1`)
+	expireScript = redis.NewScript(luaValues + `
+local v = redis.call("GET", KEYS[1])
+if not v then
+	return 0
+end
+local ttl = tonumber(ARGV[1])
+if ttl <= 0 then
+	redis.call("DEL", KEYS[1])
+	return 1
+end
+local _, payload = split(v)
+redis.call("SET", KEYS[1], with_lifetime(ARGV[1], payload), "PX", ttl)
+return 1`)
+	touchScript = redis.NewScript(luaValues + `
+local v = redis.call("GET", KEYS[1])
+if not v then
+	return 0
+end
+local ttl = lifetime(split(v))
+if ttl then
+	redis.call("PEXPIRE", KEYS[1], ttl)
+end
+return 1`)
+	persistScript = redis.NewScript(luaValues + `
+local v = redis.call("GET", KEYS[1])
+if not v then
+	return 0
+end
+local _, payload = split(v)
+redis.call("SET", KEYS[1], payload)
+return 1`)
+)
+
+const (
+	// scan batch size for prefix operations
+	scanCount = 500
+	// separator of prefix and key in redis keys
+	keySeparator = "-"
+	// marker of values with lifetime header, followed by item ttl in milliseconds, ":" and encoded value.
+	// items put with ttl keep their ttl in value, so Touch can reset item ttl
+	lifetimeMarker byte = 4
+)
+
+// prefix escaper, escaped prefixes never contain key separator, so flush and scan of prefix never match keys of other prefixes
+var prefixEscaper = strings.NewReplacer("%", "%25", keySeparator, "%2D")
+
+// redis glob pattern special characters escaper
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
 type rCache struct {
 	prefix string
 	client *redis.Client
+	codec  codec
+	ctx    context.Context
 }
 
 func (rc rCache) err(pattern string, params ...any) error {
 	return utils.TaggedError([]string{"RedisCache"}, pattern, params...)
 }
 
-func (rc *rCache) init(prefix string, opt redis.Options) {
-	rc.prefix = prefix
+func (rc *rCache) init(prefix string, opt redis.Options, conf config) {
+	rc.prefix = prefixEscaper.Replace(prefix)
 	rc.client = redis.NewClient(&opt)
+	rc.codec = newCodec(conf)
+	rc.ctx = context.Background()
 }
 
 func (rc rCache) perfixer(key string) string {
-	return utils.ConcatStr("-", rc.prefix, key)
+	return utils.ConcatStr(keySeparator, rc.prefix, key)
+}
+
+// ttlMillis get ttl in milliseconds, positive ttl never rounded to zero
+func ttlMillis(ttl time.Duration) int64 {
+	if ttl > 0 && ttl < time.Millisecond {
+		return 1
+	}
+	return int64(ttl / time.Millisecond)
+}
+
+func (rc rCache) encode(value any) ([]byte, error) {
+	if encoded, err := rc.codec.encode(value); err != nil {
+		return nil, rc.err("%w", err)
+	} else {
+		return encoded, nil
+	}
+}
+
+func (rc rCache) decode(data []byte) (any, error) {
+	if v, err := rc.codec.decode(data); err != nil {
+		return nil, rc.err("%w", err)
+	} else {
+		return v, nil
+	}
+}
+
+// eval run script on key and return false if script check failed
+func (rc rCache) eval(script *redis.Script, key string, args ...any) (bool, error) {
+	res, err := script.Run(
+		rc.ctx,
+		rc.client,
+		[]string{rc.perfixer(key)},
+		args...,
+	).Int()
+	if err != nil {
+		return false, rc.err("%w", err)
+	}
+
+	if res < 0 {
+		return false, rc.err("%s: %w", key, ErrNotNumeric)
+	}
+	return res == 1, nil
+}
+
+func (rc rCache) runScript(script *redis.Script, keys []string, args ...any) (any, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = rc.perfixer(key)
+	}
+
+	if res, err := script.Run(rc.ctx, rc.client, prefixed, args...).Result(); err != nil {
+		return nil, rc.err("%w", err)
+	} else {
+		return res, nil
+	}
+}
+
+// withLifetime get stored value of encoded value with ttl and its expiration with millisecond precision.
+// lifetime header added to values with positive ttl, non-positive ttl never expires
+func withLifetime(encoded []byte, ttl time.Duration) ([]byte, time.Duration) {
+	if ttl <= 0 {
+		return encoded, 0
+	}
+
+	ms := ttlMillis(ttl)
+	value := make([]byte, 0, len(encoded)+22)
+	value = append(value, lifetimeMarker)
+	value = strconv.AppendInt(value, ms, 10)
+	value = append(value, ':')
+	return append(value, encoded...), time.Duration(ms) * time.Millisecond
+}
+
+// withoutLifetime get encoded value of stored value without lifetime header
+func withoutLifetime(data []byte) []byte {
+	if len(data) > 0 && data[0] == lifetimeMarker {
+		if i := bytes.IndexByte(data, ':'); i > 0 {
+			return data[i+1:]
+		}
+	}
+	return data
 }
 
 func (rc rCache) Put(key string, value any, ttl time.Duration) error {
-	if err := rc.client.SetEX(
-		context.TODO(),
-		rc.perfixer(key),
-		value,
-		ttl,
-	).Err(); err != nil {
-		return rc.err(err.Error())
+	encoded, err := rc.encode(value)
+	if err != nil {
+		return err
+	}
+
+	stored, expiration := withLifetime(encoded, ttl)
+	if err := rc.client.Set(rc.ctx, rc.perfixer(key), stored, expiration).Err(); err != nil {
+		return rc.err("%w", err)
 	}
 	return nil
 }
 
 func (rc rCache) PutForever(key string, value any) error {
-	if err := rc.client.Set(
-		context.TODO(),
-		rc.perfixer(key),
-		value,
-		0,
-	).Err(); err != nil {
-		return rc.err(err.Error())
+	encoded, err := rc.encode(value)
+	if err != nil {
+		return err
+	}
+
+	if err := rc.client.Set(rc.ctx, rc.perfixer(key), encoded, 0).Err(); err != nil {
+		return rc.err("%w", err)
+	}
+	return nil
+}
+
+func (rc rCache) PutMany(values map[string]any, ttl time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	encoded := make(map[string][]byte, len(values))
+	for k, v := range values {
+		if e, err := rc.encode(v); err != nil {
+			return err
+		} else {
+			encoded[k] = e
+		}
+	}
+
+	if _, err := rc.client.Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for k, v := range encoded {
+			stored, expiration := withLifetime(v, ttl)
+			pipe.Set(rc.ctx, rc.perfixer(k), stored, expiration)
+		}
+		return nil
+	}); err != nil {
+		return rc.err("%w", err)
 	}
 	return nil
 }
 
 func (rc rCache) Set(key string, value any) (bool, error) {
-	exists, err := rc.Exists(key)
-	if err != nil || !exists {
+	encoded, err := rc.encode(value)
+	if err != nil {
 		return false, err
 	}
+	return rc.eval(setScript, key, encoded)
+}
 
-	err = rc.client.Set(
-		context.TODO(),
-		rc.perfixer(key),
-		value,
-		redis.KeepTTL,
-	).Err()
+func (rc rCache) Add(key string, value any, ttl time.Duration) (bool, error) {
+	encoded, err := rc.encode(value)
+	if err != nil {
+		return false, err
+	}
+
+	stored, _ := withLifetime(encoded, ttl)
+	return rc.eval(addScript, key, stored, ttlMillis(ttl))
+}
+
+func (rc rCache) CompareAndSwap(key string, old any, new any) (bool, error) {
+	encodedOld, err := rc.encode(old)
+	if err != nil {
+		return false, err
+	}
 
+	encodedNew, err := rc.encode(new)
 	if err != nil {
-		err = rc.err(err.Error())
+		return false, err
 	}
 
-	return true, err
+	plain, ok := plainForm(old)
+	if !ok {
+		plain = encodedOld
+	}
+	return rc.eval(casScript, key, encodedOld, encodedNew, plain)
 }
 
 func (rc rCache) Get(key string) (any, error) {
-	v, err := rc.client.Get(
-		context.TODO(),
-		rc.perfixer(key),
-	).Result()
-
-	if errors.Is(err, redis.Nil) {
-		return nil, nil
+	data, _, err := rc.getRaw(key)
+	if err != nil || data == nil {
+		return nil, err
 	}
+	return rc.decode(data)
+}
 
+func (rc rCache) GetMany(keys []string) (map[string]any, error) {
+	raws, err := rc.getManyRaw(keys)
 	if err != nil {
-		err = rc.err(err.Error())
+		return nil, err
 	}
 
-	return v, err
+	res := make(map[string]any, len(raws))
+	for k, raw := range raws {
+		if res[k], err = rc.decode(raw.data); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
 }
 
 func (rc rCache) Exists(key string) (bool, error) {
 	if exists, err := rc.client.Exists(
-		context.TODO(),
+		rc.ctx,
 		rc.perfixer(key),
 	).Result(); err != nil {
-		return false, rc.err(err.Error())
+		return false, rc.err("%w", err)
 	} else {
 		return exists > 0, nil
 	}
@@ -102,14 +391,100 @@ func (rc rCache) Exists(key string) (bool, error) {
 
 func (rc rCache) Forget(key string) error {
 	if err := rc.client.Del(
-		context.TODO(),
+		rc.ctx,
 		rc.perfixer(key),
 	).Err(); err != nil && !errors.Is(err, redis.Nil) {
-		return rc.err(err.Error())
+		return rc.err("%w", err)
 	}
 	return nil
 }
 
+func (rc rCache) ForgetMany(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = rc.perfixer(k)
+	}
+
+	if err := rc.client.Del(
+		rc.ctx,
+		prefixed...,
+	).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		return rc.err("%w", err)
+	}
+	return nil
+}
+
+func (rc rCache) Keys(pattern string) ([]string, error) {
+	return collectKeys(rc, pattern)
+}
+
+// Scan iterate keys with redis SCAN command, key may passed to fn more than once
+func (rc rCache) Scan(pattern string, fn func(key string) bool) error {
+	prefix := ""
+	if strings.TrimSpace(rc.prefix) != "" {
+		prefix = rc.prefix + keySeparator
+	}
+
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	var cursor uint64
+	for {
+		keys, next, err := rc.client.Scan(
+			rc.ctx,
+			cursor,
+			globEscaper.Replace(prefix)+pattern,
+			scanCount,
+		).Result()
+		if err != nil {
+			return rc.err("%w", err)
+		}
+
+		for _, key := range keys {
+			if !fn(strings.TrimPrefix(key, prefix)) {
+				return nil
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Flush scan and delete prefix keys in batches, flush without prefix not allowed
+func (rc rCache) Flush() error {
+	if strings.TrimSpace(rc.prefix) == "" {
+		return rc.err("flush without prefix not allowed")
+	}
+
+	pattern := globEscaper.Replace(rc.prefix) + keySeparator + "*"
+	var cursor uint64
+	for {
+		keys, next, err := rc.client.Scan(rc.ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return rc.err("%w", err)
+		}
+
+		if len(keys) > 0 {
+			if err := rc.client.Del(rc.ctx, keys...).Err(); err != nil && !errors.Is(err, redis.Nil) {
+				return rc.err("%w", err)
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
 func (rc rCache) Pull(key string) (any, error) {
 	if v, err := rc.Get(key); err != nil {
 		return nil, err
@@ -119,14 +494,65 @@ func (rc rCache) Pull(key string) (any, error) {
 }
 
 func (rc rCache) TTL(key string) (time.Duration, error) {
-	if ttl, err := rc.client.TTL(
-		context.TODO(),
+	ttl, err := rc.client.PTTL(
+		rc.ctx,
 		rc.perfixer(key),
-	).Result(); err != nil {
-		return 0, rc.err(err.Error())
-	} else {
-		return ttl, nil
+	).Result()
+	if err != nil {
+		return 0, rc.err("%w", err)
+	}
+
+	// redis returns -2 for missing keys and -1 for keys without expiration
+	switch ttl {
+	case -2:
+		return 0, rc.err("%s: %w", key, ErrNotFound)
+	case -1:
+		return NoExpiration, nil
+	}
+	return ttl, nil
+}
+
+// ttlMany get ttl of multiple items with one pipeline
+func (rc rCache) ttlMany(keys []string) (map[string]time.Duration, error) {
+	res := make(map[string]time.Duration, len(keys))
+	if len(keys) == 0 {
+		return res, nil
+	}
+
+	cmds := make([]*redis.DurationCmd, len(keys))
+	if _, err := rc.client.Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.PTTL(rc.ctx, rc.perfixer(key))
+		}
+		return nil
+	}); err != nil {
+		return nil, rc.err("%w", err)
+	}
+
+	for i, cmd := range cmds {
+		// redis returns -2 for missing keys and -1 for keys without expiration
+		switch ttl := cmd.Val(); ttl {
+		case -2:
+			continue
+		case -1:
+			res[keys[i]] = NoExpiration
+		default:
+			res[keys[i]] = ttl
+		}
 	}
+	return res, nil
+}
+
+func (rc rCache) Expire(key string, ttl time.Duration) (bool, error) {
+	return rc.eval(expireScript, key, ttlMillis(ttl))
+}
+
+func (rc rCache) Touch(key string) (bool, error) {
+	return rc.eval(touchScript, key)
+}
+
+func (rc rCache) Persist(key string) (bool, error) {
+	return rc.eval(persistScript, key)
 }
 
 func (rc rCache) Cast(key string) (caster.Caster, error) {
@@ -135,69 +561,69 @@ func (rc rCache) Cast(key string) (caster.Caster, error) {
 }
 
 func (rc rCache) IncrementFloat(key string, value float64) (bool, error) {
-	exists, err := rc.Exists(key)
-	if err != nil || !exists {
-		return exists, err
-	}
-
-	err = rc.client.IncrByFloat(
-		context.TODO(),
-		rc.perfixer(key),
-		value,
-	).Err()
-	if err != nil {
-		err = rc.err(err.Error())
-	}
-	return true, err
+	return rc.eval(incrFloatScript, key, value)
 }
 
 func (rc rCache) Increment(key string, value int64) (bool, error) {
-	exists, err := rc.Exists(key)
-	if err != nil || !exists {
-		return exists, err
-	}
+	return rc.eval(incrScript, key, value)
+}
+
+func (rc rCache) DecrementFloat(key string, value float64) (bool, error) {
+	return rc.eval(incrFloatScript, key, -value)
+}
 
-	err = rc.client.IncrBy(
-		context.TODO(),
+func (rc rCache) Decrement(key string, value int64) (bool, error) {
+	return rc.eval(incrScript, key, -value)
+}
+
+func (rc rCache) valueCodec() (codec, bool) {
+	return rc.codec, true
+}
+
+func (rc rCache) getRaw(key string) ([]byte, codec, error) {
+	v, err := rc.client.Get(
+		rc.ctx,
 		rc.perfixer(key),
-		value,
-	).Err()
+	).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, rc.codec, nil
+	}
+
 	if err != nil {
-		err = rc.err(err.Error())
+		return nil, rc.codec, rc.err("%w", err)
 	}
-	return true, err
+	return withoutLifetime(v), rc.codec, nil
 }
 
-func (rc rCache) DecrementFloat(key string, value float64) (bool, error) {
-	exists, err := rc.Exists(key)
-	if err != nil || !exists {
-		return exists, err
+func (rc rCache) getManyRaw(keys []string) (map[string]rawItem, error) {
+	res := make(map[string]rawItem)
+	if len(keys) == 0 {
+		return res, nil
 	}
 
-	err = rc.client.IncrByFloat(
-		context.TODO(),
-		rc.perfixer(key),
-		-value,
-	).Err()
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = rc.perfixer(k)
+	}
+
+	values, err := rc.client.MGet(rc.ctx, prefixed...).Result()
 	if err != nil {
-		err = rc.err(err.Error())
+		return nil, rc.err("%w", err)
 	}
-	return true, err
-}
 
-func (rc rCache) Decrement(key string, value int64) (bool, error) {
-	exists, err := rc.Exists(key)
-	if err != nil || !exists {
-		return exists, err
+	for i, v := range values {
+		if str, ok := v.(string); ok {
+			res[keys[i]] = rawItem{data: withoutLifetime([]byte(str)), codec: rc.codec}
+		}
 	}
+	return res, nil
+}
 
-	err = rc.client.DecrBy(
-		context.TODO(),
-		rc.perfixer(key),
-		value,
-	).Err()
-	if err != nil {
-		err = rc.err(err.Error())
+func (rc rCache) WithContext(ctx context.Context) Cache {
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	return true, err
+	rc.ctx = ctx
+	return &rc
 }
@@ -1,7 +1,12 @@
 package cache_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -124,13 +129,92 @@ func TestRedisCacheTTL(t *testing.T) {
 		t.Fail()
 	}
 
-	ttl, err = redisCache().TTL("non-exists")
+	err = redisCache().Put("name", "kim", 1500*time.Millisecond)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if ttl > 0 {
-		t.Fatal("failed non exists ttl")
+	ttl, err = redisCache().TTL("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl <= time.Second || ttl > 1500*time.Millisecond {
+		t.Fatalf("failed millisecond ttl %v", ttl)
+	}
+
+	err = redisCache().PutForever("forever", "kim")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ttl, err = redisCache().TTL("forever")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl != cache.NoExpiration {
+		t.Fatalf("failed forever ttl %v", ttl)
+	}
+
+	_, err = redisCache().TTL("non-exists")
+	if !errors.Is(err, cache.ErrNotFound) {
+		t.Fatal("failed non exists ttl", err)
+	}
+}
+
+func TestRedisCacheLifetime(t *testing.T) {
+	c := cache.NewRedisCache("lifetime", redis.Options{Addr: "localhost:6379"})
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Put("counter", 1, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Increment("counter", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := c.Expire("counter", time.Second); err != nil || !ok {
+		t.Fatal("failed expire", err)
+	}
+
+	if err := client.PExpire(context.Background(), "lifetime-counter", 100*time.Millisecond).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := c.Touch("counter"); err != nil || !ok {
+		t.Fatal("failed touch", err)
+	}
+
+	ttl, err := c.TTL("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl <= 500*time.Millisecond || ttl > time.Second {
+		t.Fatalf("failed touch to expire ttl %v", ttl)
+	}
+
+	v, err := c.Get("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != int64(3) {
+		t.Fatalf("lifetime changed value %v", v)
+	}
+
+	keys, err := client.Keys(context.Background(), "lifetime-*").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 1 {
+		t.Fatalf("failed single key per item %v", keys)
 	}
 }
 
@@ -219,3 +303,257 @@ func TestRedisCacheIncDec(t *testing.T) {
 		t.Fatal("failed decrement")
 	}
 }
+
+func TestRedisCacheWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := redisCache().WithContext(ctx)
+
+	err := c.Put("ctx-val", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if _, err = c.Get("ctx-val"); err == nil {
+		t.Fatal("failed canceled context!")
+	}
+
+	v, err := redisCache().Get("ctx-val")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "kim" {
+		t.Fatalf("failed context free get %s", v)
+	}
+}
+
+func TestRedisCacheMany(t *testing.T) {
+	err := redisCache().PutMany(map[string]any{"many-a": "a", "many-b": "b"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := redisCache().GetMany([]string{"many-a", "many-b", "many-c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 || res["many-a"] != "a" || res["many-b"] != "b" {
+		t.Fatalf("failed get many %v", res)
+	}
+
+	if _, ok := res["many-c"]; ok {
+		t.Fatal("failed get many miss")
+	}
+
+	err = redisCache().ForgetMany("many-a", "many-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = redisCache().GetMany([]string{"many-a", "many-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 0 {
+		t.Fatal("failed forget many")
+	}
+}
+
+func TestRedisCacheAtomicIncrement(t *testing.T) {
+	err := redisCache().Put("atomic-val", 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := redisCache().Increment("atomic-val", 2); err != nil {
+				t.Error(err)
+			}
+			if _, err := redisCache().Decrement("atomic-val", 1); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	v, err := redisCache().Get("atomic-val")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(v) != "50" {
+		t.Fatalf("failed concurrent increment %v", v)
+	}
+
+	ttl, err := redisCache().TTL("atomic-val")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl <= 0 {
+		t.Fatal("failed increment ttl preservation")
+	}
+
+	exists, err := redisCache().Increment("atomic-non-exists", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Fatal("failed exists check!")
+	}
+
+	exists, err = redisCache().Exists("atomic-non-exists")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Fatal("increment created missing item")
+	}
+}
+
+func TestRedisCacheFlush(t *testing.T) {
+	mine := cache.NewRedisCache("flush-mine", redis.Options{Addr: "localhost:6379"})
+	other := cache.NewRedisCache("flush-other", redis.Options{Addr: "localhost:6379"})
+	for _, c := range []cache.Cache{mine, other} {
+		if err := c.Put("flush", "kim", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := mine.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := mine.Exists("flush")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Fatal("failed flush!")
+	}
+
+	exists, err = other.Exists("flush")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists {
+		t.Fatal("flush removed other prefix item")
+	}
+}
+
+func TestRedisCachePrefix(t *testing.T) {
+	app := cache.NewRedisCache("app", redis.Options{Addr: "localhost:6379"})
+	admin := cache.NewRedisCache("app-admin", redis.Options{Addr: "localhost:6379"})
+	for _, c := range []cache.Cache{app, admin} {
+		if err := c.Put("name", "kim", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := app.Keys("*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range keys {
+		if key != "name" {
+			t.Fatalf("key of other prefix scanned %q", key)
+		}
+	}
+
+	if err := app.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := admin.Get("name"); err != nil || v != "kim" {
+		t.Fatalf("flush removed item of other prefix %v %v", v, err)
+	}
+
+	if err := admin.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedisCacheKeys(t *testing.T) {
+	c := cache.NewRedisCache("keys", redis.Options{Addr: "localhost:6379"})
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	err := c.PutMany(map[string]any{"user:1": 1, "user:2": 2, "post:1": 1}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := c.Keys("user:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "user:1,user:2" {
+		t.Fatalf("failed keys %v", keys)
+	}
+
+	count := 0
+	err = c.Scan("*", func(key string) bool {
+		count++
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Fatal("failed scan stop")
+	}
+}
+
+func TestRedisCacheNotNumeric(t *testing.T) {
+	c := redisCache()
+	err := c.Put("not-numeric", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Increment("not-numeric", 1)
+	if !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed increment non numeric", err)
+	}
+
+	_, err = c.DecrementFloat("not-numeric", 0.5)
+	if !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed decrement float non numeric", err)
+	}
+
+	err = c.Put("float-item", 1.5, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Increment("float-item", 1)
+	if !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed increment float item", err)
+	}
+
+	v, err := c.Get("not-numeric")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "kim" {
+		t.Fatalf("failed non numeric value changed %v", v)
+	}
+}
@@ -0,0 +1,733 @@
+// Package cachetest provides conformance tests for cache.Cache implementations.
+package cachetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bopher/cache"
+)
+
+// RunConformance run cache contract tests against caches created by factory
+//
+// factory called once for each test and cache flushed before test, so factory must not return caches with valuable items.
+// tests use string, integer and float values and keys with "conformance-" prefix
+func RunConformance(t *testing.T, factory func() cache.Cache) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, c cache.Cache)
+	}{
+		{"PutGet", testPutGet},
+		{"PutForever", testPutForever},
+		{"PutMany", testPutMany},
+		{"Set", testSet},
+		{"Add", testAdd},
+		{"CompareAndSwap", testCompareAndSwap},
+		{"Forget", testForget},
+		{"KeysScan", testKeysScan},
+		{"Flush", testFlush},
+		{"Pull", testPull},
+		{"TTL", testTTL},
+		{"SubSecondTTL", testSubSecondTTL},
+		{"NonPositiveTTL", testNonPositiveTTL},
+		{"Expiration", testExpiration},
+		{"Expire", testExpire},
+		{"Touch", testTouch},
+		{"Persist", testPersist},
+		{"Cast", testCast},
+		{"Numbers", testNumbers},
+		{"Increment", testIncrement},
+		{"IncrementFloat", testIncrementFloat},
+		{"NotNumeric", testNotNumeric},
+		{"ConcurrentIncrement", testConcurrentIncrement},
+		{"ConcurrentAdd", testConcurrentAdd},
+		{"ConcurrentCompareAndSwap", testConcurrentCompareAndSwap},
+		{"WithContext", testWithContext},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			c := factory()
+			if c == nil {
+				t.Fatal("factory returned nil cache")
+			}
+
+			if err := c.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			test.fn(t, c)
+		})
+	}
+}
+
+// put put item or fail test
+func put(t *testing.T, c cache.Cache, key string, value any, ttl time.Duration) {
+	t.Helper()
+	if err := c.Put(key, value, ttl); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// get get item or fail test
+func get(t *testing.T, c cache.Cache, key string) any {
+	t.Helper()
+	v, err := c.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// ttlOf get item ttl or fail test
+func ttlOf(t *testing.T, c cache.Cache, key string) time.Duration {
+	t.Helper()
+	ttl, err := c.TTL(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ttl
+}
+
+// assertTTL fail test if item ttl not in (0, max]
+func assertTTL(t *testing.T, c cache.Cache, key string, max time.Duration) {
+	t.Helper()
+	if ttl := ttlOf(t, c, key); ttl <= 0 || ttl > max {
+		t.Fatalf("%s ttl %v not in (0, %v]", key, ttl, max)
+	}
+}
+
+// assertMissing fail test if item exists
+func assertMissing(t *testing.T, c cache.Cache, key string) {
+	t.Helper()
+	if v := get(t, c, key); v != nil {
+		t.Fatalf("%s exists with value %v", key, v)
+	}
+
+	if exists, err := c.Exists(key); err != nil {
+		t.Fatal(err)
+	} else if exists {
+		t.Fatalf("%s exists", key)
+	}
+}
+
+// assertNumber fail test if item is not number with expected string form
+func assertNumber(t *testing.T, c cache.Cache, key string, expected string) {
+	t.Helper()
+	if v := get(t, c, key); fmt.Sprint(v) != expected {
+		t.Fatalf("%s value %v, expected %s", key, v, expected)
+	}
+}
+
+func testPutGet(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-name", "kim", time.Minute)
+	if v := get(t, c, "conformance-name"); v != "kim" {
+		t.Fatalf("failed get %v", v)
+	}
+
+	if exists, err := c.Exists("conformance-name"); err != nil || !exists {
+		t.Fatal("failed exists", err)
+	}
+
+	put(t, c, "conformance-name", "john", time.Minute)
+	if v := get(t, c, "conformance-name"); v != "john" {
+		t.Fatalf("failed overwrite %v", v)
+	}
+
+	assertMissing(t, c, "conformance-missing")
+}
+
+func testPutForever(t *testing.T, c cache.Cache) {
+	if err := c.PutForever("conformance-forever", "kim"); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := get(t, c, "conformance-forever"); v != "kim" {
+		t.Fatalf("failed get %v", v)
+	}
+
+	if ttl := ttlOf(t, c, "conformance-forever"); ttl != cache.NoExpiration {
+		t.Fatalf("failed forever ttl %v", ttl)
+	}
+}
+
+func testPutMany(t *testing.T, c cache.Cache) {
+	err := c.PutMany(map[string]any{
+		"conformance-a": "a",
+		"conformance-b": "b",
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.GetMany([]string{"conformance-a", "conformance-b", "conformance-missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 || res["conformance-a"] != "a" || res["conformance-b"] != "b" {
+		t.Fatalf("failed get many %v", res)
+	}
+
+	if _, ok := res["conformance-missing"]; ok {
+		t.Fatal("missing item included in get many")
+	}
+	assertTTL(t, c, "conformance-b", time.Minute)
+}
+
+func testSet(t *testing.T, c cache.Cache) {
+	exists, err := c.Set("conformance-missing", "kim")
+	if err != nil || exists {
+		t.Fatal("failed set missing item", err)
+	}
+	assertMissing(t, c, "conformance-missing")
+
+	put(t, c, "conformance-name", "kim", time.Minute)
+	exists, err = c.Set("conformance-name", "john")
+	if err != nil || !exists {
+		t.Fatal("failed set", err)
+	}
+
+	if v := get(t, c, "conformance-name"); v != "john" {
+		t.Fatalf("failed set value %v", v)
+	}
+	assertTTL(t, c, "conformance-name", time.Minute)
+
+	if err := c.PutForever("conformance-forever", "kim"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Set("conformance-forever", "john"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl := ttlOf(t, c, "conformance-forever"); ttl != cache.NoExpiration {
+		t.Fatalf("failed set forever ttl %v", ttl)
+	}
+}
+
+func testAdd(t *testing.T, c cache.Cache) {
+	ok, err := c.Add("conformance-name", "kim", time.Minute)
+	if err != nil || !ok {
+		t.Fatal("failed add", err)
+	}
+
+	ok, err = c.Add("conformance-name", "john", time.Minute)
+	if err != nil || ok {
+		t.Fatal("failed add existing item", err)
+	}
+
+	if v := get(t, c, "conformance-name"); v != "kim" {
+		t.Fatalf("add changed existing item %v", v)
+	}
+	assertTTL(t, c, "conformance-name", time.Minute)
+}
+
+func testCompareAndSwap(t *testing.T, c cache.Cache) {
+	swapped, err := c.CompareAndSwap("conformance-missing", "kim", "john")
+	if err != nil || swapped {
+		t.Fatal("failed swap missing item", err)
+	}
+	assertMissing(t, c, "conformance-missing")
+
+	put(t, c, "conformance-name", "kim", time.Minute)
+	swapped, err = c.CompareAndSwap("conformance-name", "jack", "john")
+	if err != nil || swapped {
+		t.Fatal("failed swap with different old value", err)
+	}
+
+	swapped, err = c.CompareAndSwap("conformance-name", "kim", "john")
+	if err != nil || !swapped {
+		t.Fatal("failed swap", err)
+	}
+
+	if v := get(t, c, "conformance-name"); v != "john" {
+		t.Fatalf("failed swap value %v", v)
+	}
+	assertTTL(t, c, "conformance-name", time.Minute)
+}
+
+func testForget(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-a", "a", time.Minute)
+	put(t, c, "conformance-b", "b", time.Minute)
+	put(t, c, "conformance-c", "c", time.Minute)
+
+	if err := c.Forget("conformance-a"); err != nil {
+		t.Fatal(err)
+	}
+	assertMissing(t, c, "conformance-a")
+
+	if err := c.ForgetMany("conformance-b", "conformance-c", "conformance-missing"); err != nil {
+		t.Fatal(err)
+	}
+	assertMissing(t, c, "conformance-b")
+	assertMissing(t, c, "conformance-c")
+
+	if err := c.Forget("conformance-missing"); err != nil {
+		t.Fatal("failed forget missing item", err)
+	}
+}
+
+func testKeysScan(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-scan-a", "a", time.Minute)
+	put(t, c, "conformance-scan-b", "b", time.Minute)
+	put(t, c, "conformance-other", "c", time.Minute)
+
+	keys, err := c.Keys("conformance-scan-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[conformance-scan-a conformance-scan-b]" {
+		t.Fatalf("failed keys %v", keys)
+	}
+
+	calls := 0
+	err = c.Scan("conformance-*", func(key string) bool {
+		calls++
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("scan not stopped, %d calls", calls)
+	}
+}
+
+func testFlush(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-a", "a", time.Minute)
+	if err := c.PutForever("conformance-b", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	assertMissing(t, c, "conformance-a")
+	assertMissing(t, c, "conformance-b")
+}
+
+func testPull(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-name", "kim", time.Minute)
+	v, err := c.Pull("conformance-name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "kim" {
+		t.Fatalf("failed pull %v", v)
+	}
+	assertMissing(t, c, "conformance-name")
+
+	if v, err := c.Pull("conformance-missing"); err != nil || v != nil {
+		t.Fatal("failed pull missing item", v, err)
+	}
+}
+
+func testTTL(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-name", "kim", time.Minute)
+	if ttl := ttlOf(t, c, "conformance-name"); ttl < 50*time.Second || ttl > time.Minute {
+		t.Fatalf("failed ttl %v", ttl)
+	}
+
+	if _, err := c.TTL("conformance-missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatal("failed missing item ttl", err)
+	}
+}
+
+func testSubSecondTTL(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-name", "kim", 1500*time.Millisecond)
+	if ttl := ttlOf(t, c, "conformance-name"); ttl <= time.Second || ttl > 1500*time.Millisecond {
+		t.Fatalf("sub-second ttl part lost %v", ttl)
+	}
+
+	ok, err := c.Add("conformance-added", "kim", 1500*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatal("failed add", err)
+	}
+
+	if ttl := ttlOf(t, c, "conformance-added"); ttl <= time.Second || ttl > 1500*time.Millisecond {
+		t.Fatalf("sub-second add ttl part lost %v", ttl)
+	}
+}
+
+func testNonPositiveTTL(t *testing.T, c cache.Cache) {
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		put(t, c, "conformance-name", "kim", ttl)
+		if v := get(t, c, "conformance-name"); v != "kim" {
+			t.Fatalf("failed put with ttl %v, value %v", ttl, v)
+		}
+
+		if got := ttlOf(t, c, "conformance-name"); got != cache.NoExpiration {
+			t.Fatalf("put with ttl %v expires in %v", ttl, got)
+		}
+
+		err := c.PutMany(map[string]any{"conformance-a": "a"}, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := ttlOf(t, c, "conformance-a"); got != cache.NoExpiration {
+			t.Fatalf("put many with ttl %v expires in %v", ttl, got)
+		}
+
+		if err := c.Forget("conformance-added"); err != nil {
+			t.Fatal(err)
+		}
+
+		ok, err := c.Add("conformance-added", "kim", ttl)
+		if err != nil || !ok {
+			t.Fatal("failed add", err)
+		}
+
+		if v := get(t, c, "conformance-added"); v != "kim" {
+			t.Fatalf("failed add with ttl %v, value %v", ttl, v)
+		}
+
+		if got := ttlOf(t, c, "conformance-added"); got != cache.NoExpiration {
+			t.Fatalf("add with ttl %v expires in %v", ttl, got)
+		}
+	}
+}
+
+func testExpiration(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-name", "kim", 100*time.Millisecond)
+	ok, err := c.Add("conformance-added", "kim", 100*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatal("failed add", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	for _, key := range []string{"conformance-name", "conformance-added"} {
+		assertMissing(t, c, key)
+		if _, err := c.TTL(key); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("%s ttl of expired item %v", key, err)
+		}
+	}
+
+	ok, err = c.Add("conformance-added", "john", time.Minute)
+	if err != nil || !ok {
+		t.Fatal("failed add expired item", err)
+	}
+}
+
+func testExpire(t *testing.T, c cache.Cache) {
+	ok, err := c.Expire("conformance-missing", time.Minute)
+	if err != nil || ok {
+		t.Fatal("failed expire missing item", err)
+	}
+	assertMissing(t, c, "conformance-missing")
+
+	if err := c.PutForever("conformance-name", "kim"); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err = c.Expire("conformance-name", 10*time.Second)
+	if err != nil || !ok {
+		t.Fatal("failed expire", err)
+	}
+	assertTTL(t, c, "conformance-name", 10*time.Second)
+
+	if v := get(t, c, "conformance-name"); v != "kim" {
+		t.Fatalf("expire changed value %v", v)
+	}
+
+	ok, err = c.Expire("conformance-name", 0)
+	if err != nil || !ok {
+		t.Fatal("failed expire with zero ttl", err)
+	}
+	assertMissing(t, c, "conformance-name")
+
+	for _, fn := range []func(key string) (bool, error){c.Touch, c.Persist} {
+		if ok, err := fn("conformance-name"); err != nil || ok {
+			t.Fatal("failed change ttl of removed item", err)
+		}
+	}
+}
+
+func testTouch(t *testing.T, c cache.Cache) {
+	ok, err := c.Touch("conformance-missing")
+	if err != nil || ok {
+		t.Fatal("failed touch missing item", err)
+	}
+	assertMissing(t, c, "conformance-missing")
+
+	put(t, c, "conformance-name", "kim", time.Hour)
+	if _, err := c.Expire("conformance-name", 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err = c.Touch("conformance-name")
+	if err != nil || !ok {
+		t.Fatal("failed touch", err)
+	}
+	assertTTL(t, c, "conformance-name", 10*time.Second)
+
+	if err := c.PutForever("conformance-forever", "kim"); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err = c.Touch("conformance-forever")
+	if err != nil || !ok {
+		t.Fatal("failed touch forever item", err)
+	}
+
+	if ttl := ttlOf(t, c, "conformance-forever"); ttl != cache.NoExpiration {
+		t.Fatalf("touch changed forever ttl %v", ttl)
+	}
+}
+
+func testPersist(t *testing.T, c cache.Cache) {
+	ok, err := c.Persist("conformance-missing")
+	if err != nil || ok {
+		t.Fatal("failed persist missing item", err)
+	}
+	assertMissing(t, c, "conformance-missing")
+
+	put(t, c, "conformance-name", "kim", time.Minute)
+	ok, err = c.Persist("conformance-name")
+	if err != nil || !ok {
+		t.Fatal("failed persist", err)
+	}
+
+	if ttl := ttlOf(t, c, "conformance-name"); ttl != cache.NoExpiration {
+		t.Fatalf("failed persist ttl %v", ttl)
+	}
+
+	if v := get(t, c, "conformance-name"); v != "kim" {
+		t.Fatalf("persist changed value %v", v)
+	}
+}
+
+func testCast(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-int", 12, time.Minute)
+	v, err := c.Cast("conformance-int")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if i, err := v.Int64(); err != nil || i != 12 {
+		t.Fatal("failed cast", i, err)
+	}
+
+	v, err = c.Cast("conformance-missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !v.IsNil() {
+		t.Fatal("failed cast missing item")
+	}
+}
+
+func testNumbers(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-int", 42, time.Minute)
+	v := get(t, c, "conformance-int")
+	if v != int64(42) {
+		t.Fatalf("failed integer round-trip %#v", v)
+	}
+
+	swapped, err := c.CompareAndSwap("conformance-int", v, 43)
+	if err != nil || !swapped {
+		t.Fatal("failed swap with read value", err)
+	}
+
+	if _, err := c.Increment("conformance-int", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := get(t, c, "conformance-int"); v != int64(44) {
+		t.Fatalf("failed incremented integer %#v", v)
+	}
+
+	put(t, c, "conformance-float", 1.5, time.Minute)
+	if v := get(t, c, "conformance-float"); v != 1.5 {
+		t.Fatalf("failed float round-trip %#v", v)
+	}
+
+	put(t, c, "conformance-name", "42", time.Minute)
+	if v := get(t, c, "conformance-name"); v != "42" {
+		t.Fatalf("failed numeric string round-trip %#v", v)
+	}
+}
+
+func testIncrement(t *testing.T, c cache.Cache) {
+	exists, err := c.Increment("conformance-missing", 1)
+	if err != nil || exists {
+		t.Fatal("failed increment missing item", err)
+	}
+	assertMissing(t, c, "conformance-missing")
+
+	put(t, c, "conformance-int", 5, time.Minute)
+	exists, err = c.Increment("conformance-int", 6)
+	if err != nil || !exists {
+		t.Fatal("failed increment", err)
+	}
+	assertNumber(t, c, "conformance-int", "11")
+
+	exists, err = c.Decrement("conformance-int", 2)
+	if err != nil || !exists {
+		t.Fatal("failed decrement", err)
+	}
+	assertNumber(t, c, "conformance-int", "9")
+	assertTTL(t, c, "conformance-int", time.Minute)
+}
+
+func testIncrementFloat(t *testing.T, c cache.Cache) {
+	exists, err := c.IncrementFloat("conformance-missing", 1)
+	if err != nil || exists {
+		t.Fatal("failed increment float missing item", err)
+	}
+	assertMissing(t, c, "conformance-missing")
+
+	put(t, c, "conformance-float", 1.5, time.Minute)
+	exists, err = c.IncrementFloat("conformance-float", 0.25)
+	if err != nil || !exists {
+		t.Fatal("failed increment float", err)
+	}
+	assertNumber(t, c, "conformance-float", "1.75")
+
+	exists, err = c.DecrementFloat("conformance-float", 0.5)
+	if err != nil || !exists {
+		t.Fatal("failed decrement float", err)
+	}
+	assertNumber(t, c, "conformance-float", "1.25")
+	assertTTL(t, c, "conformance-float", time.Minute)
+
+	put(t, c, "conformance-int", 2, time.Minute)
+	if _, err := c.IncrementFloat("conformance-int", 0.5); err != nil {
+		t.Fatal("failed increment float integer item", err)
+	}
+	assertNumber(t, c, "conformance-int", "2.5")
+}
+
+func testNotNumeric(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-name", "kim", time.Minute)
+	if _, err := c.Increment("conformance-name", 1); !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed increment non numeric item", err)
+	}
+
+	if _, err := c.DecrementFloat("conformance-name", 1); !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed decrement float non numeric item", err)
+	}
+
+	if v := get(t, c, "conformance-name"); v != "kim" {
+		t.Fatalf("numeric operation changed value %v", v)
+	}
+
+	put(t, c, "conformance-float", 1.5, time.Minute)
+	if _, err := c.Increment("conformance-float", 1); !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("failed increment float item", err)
+	}
+}
+
+func testConcurrentIncrement(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-counter", 0, time.Minute)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Increment("conformance-counter", 2); err != nil {
+				t.Error(err)
+			}
+			if _, err := c.Decrement("conformance-counter", 1); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assertNumber(t, c, "conformance-counter", "50")
+	assertTTL(t, c, "conformance-counter", time.Minute)
+}
+
+func testConcurrentAdd(t *testing.T, c cache.Cache) {
+	var added int
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok, err := c.Add("conformance-lock", i, time.Minute)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if ok {
+				mutex.Lock()
+				added++
+				mutex.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if added != 1 {
+		t.Fatalf("%d concurrent adds succeeded", added)
+	}
+}
+
+func testConcurrentCompareAndSwap(t *testing.T, c cache.Cache) {
+	put(t, c, "conformance-counter", 0, time.Minute)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				v, err := c.Get("conformance-counter")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				n, _ := v.(int64)
+				if ok, err := c.CompareAndSwap("conformance-counter", v, n+1); err != nil {
+					t.Error(err)
+					return
+				} else if ok {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assertNumber(t, c, "conformance-counter", "20")
+}
+
+func testWithContext(t *testing.T, c cache.Cache) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cc := c.WithContext(ctx)
+	put(t, cc, "conformance-name", "kim", time.Minute)
+	if v := get(t, c, "conformance-name"); v != "kim" {
+		t.Fatalf("failed context cache put %v", v)
+	}
+
+	cancel()
+	if err := cc.Put("conformance-name", "john", time.Minute); !errors.Is(err, context.Canceled) {
+		t.Fatal("failed canceled context put", err)
+	}
+
+	if _, err := cc.Get("conformance-name"); !errors.Is(err, context.Canceled) {
+		t.Fatal("failed canceled context get", err)
+	}
+
+	if ttl, err := cc.TTL("conformance-name"); !errors.Is(err, context.Canceled) || ttl != 0 {
+		t.Fatal("failed canceled context ttl", ttl, err)
+	}
+
+	if v := get(t, c, "conformance-name"); v != "kim" {
+		t.Fatalf("canceled context changed value %v", v)
+	}
+}
@@ -0,0 +1,490 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bopher/caster"
+	"github.com/bopher/utils"
+)
+
+type tCache struct {
+	local    Cache
+	remote   Cache
+	localTTL time.Duration
+}
+
+func (tc tCache) err(pattern string, params ...any) error {
+	return utils.TaggedError([]string{"TieredCache"}, pattern, params...)
+}
+
+func (tc *tCache) init(local Cache, remote Cache, localTTL time.Duration) {
+	tc.local = local
+	tc.remote = remote
+	tc.localTTL = localTTL
+}
+
+// ttlFor get local ttl for item with remote ttl, local ttl never exceeds remote ttl
+func (tc tCache) ttlFor(ttl time.Duration) time.Duration {
+	if ttl <= 0 || ttl > tc.localTTL {
+		return tc.localTTL
+	}
+	return ttl
+}
+
+// putLocal put item to local cache with ttl of at most remote ttl, items not cached locally if local ttl is not positive
+func (tc tCache) putLocal(key string, value any, ttl time.Duration) error {
+	if tc.localTTL <= 0 {
+		return nil
+	}
+
+	if err := tc.local.Put(key, value, tc.ttlFor(ttl)); err != nil {
+		return tc.err("%w", err)
+	}
+	return nil
+}
+
+// invalidate remove stale item from local cache after remote change
+func (tc tCache) invalidate(key string, exists bool, err error) (bool, error) {
+	if err != nil {
+		return exists, err
+	}
+
+	if err := tc.local.Forget(key); err != nil {
+		return exists, tc.err("%w", err)
+	}
+	return exists, nil
+}
+
+func (tc tCache) Put(key string, value any, ttl time.Duration) error {
+	if err := tc.remote.Put(key, value, ttl); err != nil {
+		return tc.err("%w", err)
+	}
+	return tc.putLocal(key, value, ttl)
+}
+
+func (tc tCache) PutForever(key string, value any) error {
+	if err := tc.remote.PutForever(key, value); err != nil {
+		return tc.err("%w", err)
+	}
+	return tc.putLocal(key, value, NoExpiration)
+}
+
+func (tc tCache) PutMany(values map[string]any, ttl time.Duration) error {
+	if err := tc.remote.PutMany(values, ttl); err != nil {
+		return tc.err("%w", err)
+	}
+
+	if tc.localTTL <= 0 {
+		return nil
+	}
+
+	if err := tc.local.PutMany(values, tc.ttlFor(ttl)); err != nil {
+		return tc.err("%w", err)
+	}
+	return nil
+}
+
+func (tc tCache) Set(key string, value any) (bool, error) {
+	exists, err := tc.remote.Set(key, value)
+	if err != nil {
+		return false, tc.err("%w", err)
+	}
+
+	if !exists {
+		return tc.invalidate(key, false, nil)
+	}
+
+	if _, err := tc.local.Set(key, value); err != nil {
+		return true, tc.err("%w", err)
+	}
+	return true, nil
+}
+
+func (tc tCache) Add(key string, value any, ttl time.Duration) (bool, error) {
+	ok, err := tc.remote.Add(key, value, ttl)
+	if err != nil {
+		return false, tc.err("%w", err)
+	}
+
+	if ok {
+		return true, tc.putLocal(key, value, ttl)
+	}
+	return false, nil
+}
+
+func (tc tCache) CompareAndSwap(key string, old any, new any) (bool, error) {
+	swapped, err := tc.remote.CompareAndSwap(key, old, new)
+	if err != nil {
+		err = tc.err("%w", err)
+	}
+	return tc.invalidate(key, swapped, err)
+}
+
+func (tc tCache) Get(key string) (any, error) {
+	v, err := tc.local.Get(key)
+	if err != nil {
+		return nil, tc.err("%w", err)
+	}
+
+	if v != nil {
+		return v, nil
+	}
+
+	v, err = tc.remote.Get(key)
+	if err != nil {
+		return nil, tc.err("%w", err)
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+	return v, tc.backfill(key, v)
+}
+
+// GetMany back-fill remote items to local cache with ttl of at most remote ttl, remote ttls read in batch
+func (tc tCache) GetMany(keys []string) (map[string]any, error) {
+	res, err := tc.local.GetMany(keys)
+	if err != nil {
+		return nil, tc.err("%w", err)
+	}
+
+	misses := make([]string, 0)
+	for _, k := range keys {
+		if _, ok := res[k]; !ok {
+			misses = append(misses, k)
+		}
+	}
+
+	if len(misses) == 0 {
+		return res, nil
+	}
+
+	remotes, err := tc.remote.GetMany(misses)
+	if err != nil {
+		return nil, tc.err("%w", err)
+	}
+
+	if err := tc.backfillMany(remotes); err != nil {
+		return nil, err
+	}
+
+	for k, v := range remotes {
+		res[k] = v
+	}
+	return res, nil
+}
+
+func (tc tCache) Exists(key string) (bool, error) {
+	exists, err := tc.local.Exists(key)
+	if err != nil {
+		return false, tc.err("%w", err)
+	}
+
+	if exists {
+		return true, nil
+	}
+
+	exists, err = tc.remote.Exists(key)
+	if err != nil {
+		return false, tc.err("%w", err)
+	}
+	return exists, nil
+}
+
+func (tc tCache) Forget(key string) error {
+	if err := tc.remote.Forget(key); err != nil {
+		return tc.err("%w", err)
+	}
+
+	if err := tc.local.Forget(key); err != nil {
+		return tc.err("%w", err)
+	}
+	return nil
+}
+
+func (tc tCache) ForgetMany(keys ...string) error {
+	if err := tc.remote.ForgetMany(keys...); err != nil {
+		return tc.err("%w", err)
+	}
+
+	if err := tc.local.ForgetMany(keys...); err != nil {
+		return tc.err("%w", err)
+	}
+	return nil
+}
+
+func (tc tCache) Keys(pattern string) ([]string, error) {
+	if keys, err := tc.remote.Keys(pattern); err != nil {
+		return nil, tc.err("%w", err)
+	} else {
+		return keys, nil
+	}
+}
+
+// Scan iterate remote cache keys
+func (tc tCache) Scan(pattern string, fn func(key string) bool) error {
+	if err := tc.remote.Scan(pattern, fn); err != nil {
+		return tc.err("%w", err)
+	}
+	return nil
+}
+
+func (tc tCache) Flush() error {
+	if err := tc.remote.Flush(); err != nil {
+		return tc.err("%w", err)
+	}
+
+	if err := tc.local.Flush(); err != nil {
+		return tc.err("%w", err)
+	}
+	return nil
+}
+
+func (tc tCache) Pull(key string) (any, error) {
+	v, err := tc.remote.Pull(key)
+	if err != nil {
+		return nil, tc.err("%w", err)
+	}
+
+	if err := tc.local.Forget(key); err != nil {
+		return nil, tc.err("%w", err)
+	}
+	return v, nil
+}
+
+func (tc tCache) TTL(key string) (time.Duration, error) {
+	if ttl, err := tc.remote.TTL(key); err != nil {
+		return 0, tc.err("%w", err)
+	} else {
+		return ttl, nil
+	}
+}
+
+func (tc tCache) Expire(key string, ttl time.Duration) (bool, error) {
+	exists, err := tc.remote.Expire(key, ttl)
+	if err != nil {
+		err = tc.err("%w", err)
+	}
+	return tc.invalidate(key, exists, err)
+}
+
+func (tc tCache) Touch(key string) (bool, error) {
+	exists, err := tc.remote.Touch(key)
+	if err != nil {
+		err = tc.err("%w", err)
+	}
+	return tc.invalidate(key, exists, err)
+}
+
+func (tc tCache) Persist(key string) (bool, error) {
+	exists, err := tc.remote.Persist(key)
+	if err != nil {
+		err = tc.err("%w", err)
+	}
+	return tc.invalidate(key, exists, err)
+}
+
+func (tc tCache) Cast(key string) (caster.Caster, error) {
+	v, err := tc.Get(key)
+	return caster.NewCaster(v), err
+}
+
+func (tc tCache) IncrementFloat(key string, value float64) (bool, error) {
+	exists, err := tc.remote.IncrementFloat(key, value)
+	if err != nil {
+		err = tc.err("%w", err)
+	}
+	return tc.invalidate(key, exists, err)
+}
+
+func (tc tCache) Increment(key string, value int64) (bool, error) {
+	exists, err := tc.remote.Increment(key, value)
+	if err != nil {
+		err = tc.err("%w", err)
+	}
+	return tc.invalidate(key, exists, err)
+}
+
+func (tc tCache) DecrementFloat(key string, value float64) (bool, error) {
+	exists, err := tc.remote.DecrementFloat(key, value)
+	if err != nil {
+		err = tc.err("%w", err)
+	}
+	return tc.invalidate(key, exists, err)
+}
+
+func (tc tCache) Decrement(key string, value int64) (bool, error) {
+	exists, err := tc.remote.Decrement(key, value)
+	if err != nil {
+		err = tc.err("%w", err)
+	}
+	return tc.invalidate(key, exists, err)
+}
+
+// backfill put remote value to local cache with ttl of at most remote ttl,
+// items removed from remote after read not back-filled
+func (tc tCache) backfill(key string, value any) error {
+	if tc.localTTL <= 0 {
+		return nil
+	}
+
+	ttl, err := tc.remote.TTL(key)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return tc.err("%w", err)
+	}
+
+	return tc.putLocal(key, value, ttl)
+}
+
+// backfillMany put remote values to local cache with ttl of at most remote ttl, remote ttls read in batch.
+// items removed from remote after read not back-filled
+func (tc tCache) backfillMany(values map[string]any) error {
+	if tc.localTTL <= 0 || len(values) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+
+	ttls, err := ttlMany(tc.remote, keys)
+	if err != nil {
+		return tc.err("%w", err)
+	}
+
+	for k, ttl := range ttls {
+		if err := tc.putLocal(k, values[k], ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stop background cleanup of local and remote caches that implement Closer
+func (tc tCache) Close() error {
+	var res error
+	for _, c := range []Cache{tc.local, tc.remote} {
+		if closer, ok := c.(Closer); ok {
+			if err := closer.Close(); err != nil && res == nil {
+				res = tc.err("%w", err)
+			}
+		}
+	}
+	return res
+}
+
+// Sweep remove expired items of local and remote caches that implement Sweeper
+func (tc tCache) Sweep() (SweepResult, error) {
+	var res SweepResult
+	for _, c := range []Cache{tc.local, tc.remote} {
+		if sweeper, ok := c.(Sweeper); ok {
+			swept, err := sweeper.Sweep()
+			res.Items += swept.Items
+			res.Bytes += swept.Bytes
+			if err != nil {
+				return res, tc.err("%w", err)
+			}
+		}
+	}
+	return res, nil
+}
+
+// ttlMany get ttl of multiple remote items
+func (tc tCache) ttlMany(keys []string) (map[string]time.Duration, error) {
+	if res, err := ttlMany(tc.remote, keys); err != nil {
+		return nil, tc.err("%w", err)
+	} else {
+		return res, nil
+	}
+}
+
+// valueCodec get codec of local cache, items read from local cache first
+func (tc tCache) valueCodec() (codec, bool) {
+	if c, ok := tc.local.(codecer); ok {
+		return c.valueCodec()
+	}
+	return codec{}, false
+}
+
+// getRaw read raw item from local cache or fall back to remote cache, both layers must implement rawGetter
+func (tc tCache) getRaw(key string) ([]byte, codec, error) {
+	local, lok := tc.local.(rawGetter)
+	remote, rok := tc.remote.(rawGetter)
+	if !lok || !rok {
+		return nil, codec{}, errRawUnsupported
+	}
+
+	data, c, err := local.getRaw(key)
+	if err != nil {
+		return nil, c, tc.err("%w", err)
+	}
+
+	if data != nil {
+		return data, c, nil
+	}
+
+	data, c, err = remote.getRaw(key)
+	if err != nil || data == nil {
+		if err != nil {
+			err = tc.err("%w", err)
+		}
+		return data, c, err
+	}
+
+	v, err := c.decode(data)
+	if err != nil {
+		return nil, c, tc.err("%w", err)
+	}
+	return data, c, tc.backfill(key, v)
+}
+
+// getManyRaw read raw items from local cache and fall back to remote cache for misses, both layers must implement rawGetter.
+// items decoded with codec of their layer and remote items back-filled to local cache with ttl of at most remote ttl
+func (tc tCache) getManyRaw(keys []string) (map[string]rawItem, error) {
+	local, lok := tc.local.(rawGetter)
+	remote, rok := tc.remote.(rawGetter)
+	if !lok || !rok {
+		return nil, errRawUnsupported
+	}
+
+	res, err := local.getManyRaw(keys)
+	if err != nil {
+		return nil, tc.err("%w", err)
+	}
+
+	misses := make([]string, 0)
+	for _, k := range keys {
+		if _, ok := res[k]; !ok {
+			misses = append(misses, k)
+		}
+	}
+
+	if len(misses) == 0 {
+		return res, nil
+	}
+
+	remotes, err := remote.getManyRaw(misses)
+	if err != nil {
+		return nil, tc.err("%w", err)
+	}
+
+	values := make(map[string]any, len(remotes))
+	for k, item := range remotes {
+		if values[k], err = item.codec.decode(item.data); err != nil {
+			return nil, tc.err("%w", err)
+		}
+		res[k] = item
+	}
+	return res, tc.backfillMany(values)
+}
+
+func (tc tCache) WithContext(ctx context.Context) Cache {
+	tc.local = tc.local.WithContext(ctx)
+	tc.remote = tc.remote.WithContext(ctx)
+	return &tc
+}
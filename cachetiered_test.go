@@ -0,0 +1,222 @@
+package cache_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bopher/cache"
+)
+
+func tieredCache() (cache.Cache, cache.Cache, cache.Cache) {
+	local := cache.NewMemoryCache(100, 0, 0)
+	remote := redisCache()
+	return cache.NewTieredCache(local, remote, 10*time.Second), local, remote
+}
+
+func TestTieredCacheGet(t *testing.T) {
+	c, local, remote := tieredCache()
+	err := remote.Put("tiered-name", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := c.Get("tiered-name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "kim" {
+		t.Fatalf("failed remote get %v", v)
+	}
+
+	ttl, err := local.TTL("tiered-name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl <= 0 || ttl > 10*time.Second {
+		t.Fatalf("failed local back-fill ttl %v", ttl)
+	}
+}
+
+func TestTieredCacheNoLocalTTL(t *testing.T) {
+	local := cache.NewMemoryCache(100, 0, 0)
+	c := cache.NewTieredCache(local, redisCache(), 0)
+	err := c.Put("tiered-nolocal", "kim", 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := c.Get("tiered-nolocal"); err != nil || v != "kim" {
+		t.Fatalf("failed get %v %v", v, err)
+	}
+
+	if exists, err := local.Exists("tiered-nolocal"); err != nil || exists {
+		t.Fatal("item cached locally without local ttl", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if v, err := c.Get("tiered-nolocal"); err != nil || v != nil {
+		t.Fatalf("expired item returned %v %v", v, err)
+	}
+}
+
+func TestTieredCacheGetMany(t *testing.T) {
+	c, local, remote := tieredCache()
+	err := local.ForgetMany("tiered-short", "tiered-long")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = remote.Put("tiered-short", "kim", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = remote.Put("tiered-long", "john", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.GetMany([]string{"tiered-short", "tiered-long"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 {
+		t.Fatalf("failed remote get many %v", res)
+	}
+
+	ttl, err := local.TTL("tiered-short")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl <= 0 || ttl > time.Second {
+		t.Fatalf("local back-fill ttl exceeds remote ttl %v", ttl)
+	}
+
+	ttl, err = local.TTL("tiered-long")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl <= time.Second || ttl > 10*time.Second {
+		t.Fatalf("failed local back-fill ttl %v", ttl)
+	}
+}
+
+func TestTieredCacheForget(t *testing.T) {
+	c, local, remote := tieredCache()
+	err := c.Put("tiered-name", "kim", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Forget("tiered-name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, layer := range []cache.Cache{local, remote} {
+		exists, err := layer.Exists("tiered-name")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exists {
+			t.Fatal("failed forget!")
+		}
+	}
+}
+
+func TestTieredCacheIncDec(t *testing.T) {
+	c, local, _ := tieredCache()
+	err := c.Put("tiered-int", 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := c.Increment("tiered-int", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists {
+		t.Fatal("item not exists!")
+	}
+
+	exists, err = local.Exists("tiered-int")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Fatal("failed local invalidation")
+	}
+
+	v, err := c.Get("tiered-int")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(v) != "9" {
+		t.Fatalf("failed increment %v", v)
+	}
+}
+
+func TestTieredCacheCompareAndSwap(t *testing.T) {
+	c, local, _ := tieredCache()
+	err := c.Put("tiered-cas", "a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := c.CompareAndSwap("tiered-cas", "a", "b")
+	if err != nil || !ok {
+		t.Fatal("failed compare and swap", err)
+	}
+
+	exists, err := local.Exists("tiered-cas")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Fatal("local item not invalidated")
+	}
+
+	v, err := c.Get("tiered-cas")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "b" {
+		t.Fatalf("failed compare and swap value %v", v)
+	}
+}
+
+func TestTieredCacheSweepClose(t *testing.T) {
+	local := cache.NewMemoryCache(100, 0, time.Minute)
+	remote := cache.NewFileCache("tiered", t.TempDir(), cache.WithSweepInterval(time.Minute))
+	c := cache.NewTieredCache(local, remote, 10*time.Second)
+	err := c.Put("tiered-name", "kim", 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	res, err := c.(cache.Sweeper).Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Items != 2 {
+		t.Fatalf("failed sweep both layers %d", res.Items)
+	}
+
+	if err := c.(cache.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+}
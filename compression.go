@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressor interface for cache value compressors
+type Compressor interface {
+	// ID unique compressor identifier stored with compressed values
+	ID() byte
+	// Compress compress data
+	Compress(data []byte) ([]byte, error)
+	// Decompress decompress data
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipCompressor create gzip compressor
+func GzipCompressor() Compressor {
+	return gzipCompressor{}
+}
+
+// ZstdCompressor create zstandard compressor
+func ZstdCompressor() Compressor {
+	return zstdCompressor{}
+}
+
+// builtin compressors by id
+var compressors = map[byte]Compressor{
+	gzipCompressor{}.ID(): gzipCompressor{},
+	zstdCompressor{}.ID(): zstdCompressor{},
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) ID() byte {
+	return 'g'
+}
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	b := bytes.Buffer{}
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// shared zstd encoder and decoder, both safe for concurrent EncodeAll and DecodeAll calls
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+func zstdCodec() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		if zstdEncoder, zstdErr = zstd.NewWriter(nil); zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+type zstdCompressor struct{}
+
+func (zstdCompressor) ID() byte {
+	return 'z'
+}
+
+func (zstdCompressor) Compress(data []byte) ([]byte, error) {
+	encoder, _, err := zstdCodec()
+	if err != nil {
+		return nil, err
+	}
+	return encoder.EncodeAll(data, nil), nil
+}
+
+func (zstdCompressor) Decompress(data []byte) ([]byte, error) {
+	_, decoder, err := zstdCodec()
+	if err != nil {
+		return nil, err
+	}
+	return decoder.DecodeAll(data, nil)
+}
+
+// marker of compressed values, followed by compressor id and compressed encoded value
+const compressedMarker byte = 1
+
+// compressorOf get compressor of id, c used if its id matches
+func compressorOf(c Compressor, id byte) (Compressor, error) {
+	if c != nil && c.ID() == id {
+		return c, nil
+	}
+
+	if builtin, ok := compressors[id]; ok {
+		return builtin, nil
+	}
+	return nil, fmt.Errorf("unknown compressor %q", id)
+}
+
+// codec encode and decode cache values with serializer and optional compressor
+type codec struct {
+	serializer Serializer
+	compressor Compressor
+	threshold  int
+}
+
+func newCodec(conf config) codec {
+	return codec{
+		serializer: conf.serializer,
+		compressor: conf.compressor,
+		threshold:  conf.compressThreshold,
+	}
+}
+
+// encode encode value, serialized values bigger than threshold compressed.
+// numeric values never compressed, so redis can change them atomically
+func (c codec) encode(value any) ([]byte, error) {
+	encoded, err := encodeValue(c.serializer, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.compressor == nil ||
+		len(encoded) < c.threshold ||
+		len(encoded) < 2 ||
+		encoded[0] != valueMarker {
+		return encoded, nil
+	}
+
+	compressed, err := c.compressor.Compress(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{compressedMarker, c.compressor.ID()}, compressed...), nil
+}
+
+// decompress get encoded value of compressed data, other data returned as is
+func (c codec) decompress(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != compressedMarker {
+		return data, nil
+	}
+
+	compressor, err := compressorOf(c.compressor, data[1])
+	if err != nil {
+		return nil, err
+	}
+	return compressor.Decompress(data[2:])
+}
+
+// normalize get value as decoded from cache, so stored and read values have same type
+func (c codec) normalize(value any) (any, error) {
+	encoded, err := encodeValue(c.serializer, value)
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(c.serializer, encoded)
+}
+
+// decode decode stored value
+func (c codec) decode(data []byte) (any, error) {
+	data, err := c.decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(c.serializer, data)
+}
+
+// decodeInto decode stored value into dest pointer
+func (c codec) decodeInto(data []byte, dest any) error {
+	data, err := c.decompress(data)
+	if err != nil {
+		return err
+	}
+	return decodeInto(c.serializer, data, dest)
+}
@@ -0,0 +1,93 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bopher/cache"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestCompression(t *testing.T) {
+	html := strings.Repeat("<div>cached fragment</div>", 1000)
+	for _, compressor := range []cache.Compressor{cache.GzipCompressor(), cache.ZstdCompressor()} {
+		dir := t.TempDir()
+		drivers := []cache.Cache{
+			cache.NewFileCache("compression", dir, cache.WithCompression(compressor, 1024)),
+			cache.NewRedisCache("compression", redis.Options{Addr: "localhost:6379"}, cache.WithCompression(compressor, 1024)),
+			cache.NewMemoryCache(0, 0, 0, cache.WithCompression(compressor, 1024)),
+		}
+
+		for _, c := range drivers {
+			err := c.PutMany(map[string]any{"large": html, "small": "fragment", "number": 3}, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, err := c.GetMany([]string{"large", "small"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if values["large"] != html || values["small"] != "fragment" {
+				t.Fatalf("compressor %c: failed round-trip", compressor.ID())
+			}
+
+			_, err = c.Increment("number", 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := c.Get("number")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v != int64(5) {
+				t.Fatalf("compressor %c: failed numeric value %#v", compressor.ID(), v)
+			}
+		}
+
+		// compressed values readable by drivers without compression
+		v, err := cache.NewFileCache("compression", dir).Get("large")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v != html {
+			t.Fatalf("compressor %c: failed read without compression", compressor.ID())
+		}
+	}
+
+	raw, err := redis.NewClient(&redis.Options{Addr: "localhost:6379"}).Get(context.Background(), "compression-large").Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// skip lifetime header of items with ttl
+	raw = raw[bytes.IndexByte(raw, ':')+1:]
+	if len(raw) >= len(html) || raw[0] != 1 {
+		t.Fatalf("value not compressed, size %d", len(raw))
+	}
+}
+
+func TestCompressionTyped(t *testing.T) {
+	c := cache.NewRedisCache("compression", redis.Options{Addr: "localhost:6379"}, cache.WithCompression(cache.GzipCompressor(), 0))
+	typed := cache.NewTyped[[]string](c, 0)
+	err := typed.Put("list", []string{"a", "b"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, _, err := typed.Get("list")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(v, ",") != "a,b" {
+		t.Fatalf("failed typed read of compressed value %v", v)
+	}
+}
@@ -0,0 +1,45 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bopher/cache"
+	"github.com/bopher/cache/cachetest"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestConformance(t *testing.T) {
+	dir := t.TempDir()
+	keys := map[string][]byte{"v1": encKeyV1}
+	drivers := []struct {
+		name    string
+		factory func() cache.Cache
+	}{
+		{"Redis", func() cache.Cache {
+			return cache.NewRedisCache("conformance", redis.Options{Addr: "localhost:6379"})
+		}},
+		{"File", func() cache.Cache {
+			return cache.NewFileCache("conformance", dir)
+		}},
+		{"Memory", func() cache.Cache {
+			return cache.NewMemoryCache(0, 0, 0)
+		}},
+		{"Tiered", func() cache.Cache {
+			return cache.NewTieredCache(
+				cache.NewMemoryCache(100, 0, 0),
+				cache.NewRedisCache("conformance-tiered", redis.Options{Addr: "localhost:6379"}),
+				10*time.Second,
+			)
+		}},
+		{"Encrypted", func() cache.Cache {
+			return encryptedCache(t, cache.NewMemoryCache(0, 0, 0), "v1", keys)
+		}},
+	}
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			cachetest.RunConformance(t, driver.factory)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package cache
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+const (
+	// lock retry interval
+	lockRetryInterval = 5 * time.Millisecond
+	// lock file older than this treated as abandoned lock of crashed process
+	lockStaleAfter = 30 * time.Second
+)
+
+// lockFile hold exclusive lock by creating lock file exclusively and return unlock function
+func lockFile(name string) (func() error, error) {
+	name = name + ".lock"
+	for {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() error {
+				return os.Remove(name)
+			}, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(name); err == nil && time.Since(info.ModTime()) > lockStaleAfter {
+			os.Remove(name)
+			continue
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
@@ -0,0 +1,105 @@
+package cache
+
+// matchGlob check if str matches redis style glob pattern, empty pattern matches all.
+// supports *, ?, [abc], [^abc], [a-z] and \ escape
+func matchGlob(pattern string, str string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	p, s := 0, 0
+	// position of last star in pattern and matched str position
+	star, match := -1, 0
+	for s < len(str) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				star, match = p, s
+				p++
+				continue
+			case '?':
+				p++
+				s++
+				continue
+			case '[':
+				if end, ok := matchClass(pattern, p, str[s]); end > 0 {
+					if ok {
+						p = end
+						s++
+						continue
+					}
+				} else if str[s] == '[' {
+					// unclosed class matches literal bracket
+					p++
+					s++
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) && pattern[p+1] == str[s] {
+					p += 2
+					s++
+					continue
+				}
+			default:
+				if pattern[p] == str[s] {
+					p++
+					s++
+					continue
+				}
+			}
+		}
+
+		if star < 0 {
+			return false
+		}
+		match++
+		p, s = star+1, match
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchClass match character against class started at pattern[start] and return class end position.
+// end is 0 for unclosed class
+func matchClass(pattern string, start int, c byte) (int, bool) {
+	i := start + 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+
+	matched := false
+	for first := true; i < len(pattern); first = false {
+		if pattern[i] == ']' && !first {
+			return i + 1, matched != negate
+		}
+
+		lo := pattern[i]
+		if lo == '\\' && i+1 < len(pattern) {
+			i++
+			lo = pattern[i]
+		}
+		i++
+
+		hi := lo
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi = pattern[i+1]
+			if hi == '\\' && i+2 < len(pattern) {
+				i++
+				hi = pattern[i+1]
+			}
+			i += 2
+		}
+
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+	return 0, false
+}
@@ -6,12 +6,15 @@ require (
 	github.com/bopher/caster v1.2.3
 	github.com/bopher/utils v1.7.3
 	github.com/go-redis/redis/v8 v8.11.5
+	github.com/klauspost/compress v1.15.9
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 )
 
 require (
 	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/gabriel-vasile/mimetype v1.4.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	golang.org/x/net v0.0.0-20220607020251-c690dde0001d // indirect
 	golang.org/x/text v0.3.7 // indirect
 )
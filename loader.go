@@ -0,0 +1,16 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Loader interface for compute-on-miss cache helpers
+type Loader interface {
+	// Remember get item from cache or run loader and put result with ttl on cache miss
+	Remember(key string, ttl time.Duration, loader func() (any, error)) (any, error)
+	// RememberForever get item from cache or run loader and put result with infinite ttl on cache miss
+	RememberForever(key string, loader func() (any, error)) (any, error)
+	// WithContext get a copy of loader that run cache operations with ctx
+	WithContext(ctx context.Context) Loader
+}
@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bopher/utils"
+)
+
+// lock polling interval for waiting on other process loader
+const lockPollInterval = 50 * time.Millisecond
+
+// lockReleased value of released lock, swapped with holder token before lock removed
+const lockReleased = "released"
+
+type flightCall struct {
+	wg    sync.WaitGroup
+	value any
+	err   error
+}
+
+// flightGroup run only one function call per key at same time
+type flightGroup struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do run fn for key or wait for running call of key, fn panic returned as error to caller and waiters
+func (fg *flightGroup) do(key string, fn func() (any, error)) (any, error) {
+	fg.mutex.Lock()
+	if call, ok := fg.calls[key]; ok {
+		fg.mutex.Unlock()
+		call.wg.Wait()
+		return call.value, call.err
+	}
+
+	call := new(flightCall)
+	call.wg.Add(1)
+	fg.calls[key] = call
+	fg.mutex.Unlock()
+
+	defer func() {
+		fg.mutex.Lock()
+		delete(fg.calls, key)
+		fg.mutex.Unlock()
+		call.wg.Done()
+	}()
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				call.value, call.err = nil, fmt.Errorf("loader panic: %v", r)
+			}
+		}()
+		call.value, call.err = fn()
+	}()
+
+	return call.value, call.err
+}
+
+type lDriver struct {
+	cache   Cache
+	lockTTL time.Duration
+	group   *flightGroup
+	ctx     context.Context
+	// return loaded values as decoded from cache, so loaded and cached values have same type
+	normalize bool
+}
+
+func (ld lDriver) err(key string, pattern string, params ...any) error {
+	return utils.TaggedError([]string{"Loader", key}, pattern, params...)
+}
+
+func (ld *lDriver) init(cache Cache, lockTTL time.Duration) {
+	ld.cache = cache
+	ld.lockTTL = lockTTL
+	ld.group = &flightGroup{calls: make(map[string]*flightCall)}
+	ld.ctx = context.Background()
+	ld.normalize = true
+}
+
+func (ld lDriver) lockKey(key string) string {
+	return utils.ConcatStr("-", key, "lock")
+}
+
+// lockToken generate random lock holder token
+func (ld lDriver) lockToken(key string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", ld.err(key, "%w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// unlock remove item lock only if still held by token, lock acquired by
+// other process after lock ttl passed not removed
+func (ld lDriver) unlock(key string, token string) {
+	if released, err := ld.cache.CompareAndSwap(ld.lockKey(key), token, lockReleased); err == nil && released {
+		ld.cache.Forget(ld.lockKey(key))
+	}
+}
+
+// remember get item or load and store it using put function
+func (ld lDriver) remember(key string, loader func() (any, error), put func(value any) error) (any, error) {
+	if v, err := ld.cache.Get(key); err != nil || v != nil {
+		if err != nil {
+			err = ld.err(key, "%w", err)
+		}
+		return v, err
+	}
+
+	return ld.group.do(key, func() (any, error) {
+		if ld.lockTTL > 0 {
+			return ld.loadLocked(key, loader, put)
+		}
+		return ld.load(key, loader, put)
+	})
+}
+
+func (ld lDriver) load(key string, loader func() (any, error), put func(value any) error) (any, error) {
+	v, err := loader()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := put(v); err != nil {
+		return nil, ld.err(key, "%w", err)
+	}
+
+	if !ld.normalize {
+		return v, nil
+	}
+
+	if c, ok := ld.cache.(codecer); ok {
+		if c, ok := c.valueCodec(); ok {
+			if v, err = c.normalize(v); err != nil {
+				return nil, ld.err(key, "%w", err)
+			}
+		}
+	}
+	return v, nil
+}
+
+// loadLocked run loader only if no other process holds item lock, otherwise wait for other process result
+func (ld lDriver) loadLocked(key string, loader func() (any, error), put func(value any) error) (any, error) {
+	token, err := ld.lockToken(key)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(ld.lockTTL)
+	for {
+		locked, err := ld.cache.Add(ld.lockKey(key), token, ld.lockTTL)
+		if err != nil {
+			return nil, ld.err(key, "%w", err)
+		}
+
+		if locked {
+			defer ld.unlock(key, token)
+
+			// other process may store item before lock acquired
+			if v, err := ld.cache.Get(key); err != nil || v != nil {
+				if err != nil {
+					err = ld.err(key, "%w", err)
+				}
+				return v, err
+			}
+			return ld.load(key, loader, put)
+		}
+
+		select {
+		case <-ld.ctx.Done():
+			return nil, ld.err(key, "%w", ld.ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+
+		if v, err := ld.cache.Get(key); err != nil || v != nil {
+			if err != nil {
+				err = ld.err(key, "%w", err)
+			}
+			return v, err
+		}
+
+		// lock holder did not finish in lock ttl
+		if time.Now().After(deadline) {
+			return ld.load(key, loader, put)
+		}
+	}
+}
+
+func (ld lDriver) Remember(key string, ttl time.Duration, loader func() (any, error)) (any, error) {
+	return ld.remember(key, loader, func(value any) error {
+		return ld.cache.Put(key, value, ttl)
+	})
+}
+
+func (ld lDriver) RememberForever(key string, loader func() (any, error)) (any, error) {
+	return ld.remember(key, loader, func(value any) error {
+		return ld.cache.PutForever(key, value)
+	})
+}
+
+func (ld lDriver) WithContext(ctx context.Context) Loader {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ld.cache = ld.cache.WithContext(ctx)
+	ld.ctx = ctx
+	return &ld
+}
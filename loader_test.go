@@ -0,0 +1,175 @@
+package cache_test
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bopher/cache"
+)
+
+func TestRemember(t *testing.T) {
+	c := cache.NewMemoryCache(0, 0, 0)
+	loader := cache.NewLoader(c, 0)
+
+	var calls int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := loader.Remember("remember", time.Minute, func() (any, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(50 * time.Millisecond)
+				return "kim", nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+
+			if v != "kim" {
+				t.Errorf("failed remember %v", v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("loader called %d times", calls)
+	}
+
+	exists, err := c.Exists("remember")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists {
+		t.Fatal("failed remember put")
+	}
+}
+
+func TestRememberForeverLocked(t *testing.T) {
+	err := redisCache().Forget("remember-locked")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		// each loader simulate a separate process
+		loader := cache.NewLoader(redisCache(), time.Second)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := loader.RememberForever("remember-locked", func() (any, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(100 * time.Millisecond)
+				return "kim", nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+
+			if v != "kim" {
+				t.Errorf("failed remember %v", v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("loader called %d times", calls)
+	}
+}
+
+func TestRememberPanic(t *testing.T) {
+	loader := cache.NewLoader(cache.NewMemoryCache(0, 0, 0), 0)
+	_, err := loader.Remember("remember-panic", time.Minute, func() (any, error) {
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatal("loader panic not returned as error")
+	}
+
+	// panicked call must not block next loads of key
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err := loader.Remember("remember-panic", time.Minute, func() (any, error) {
+			return "kim", nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+
+		if v != "kim" {
+			t.Errorf("failed remember %v", v)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("remember blocked after loader panic")
+	}
+}
+
+func TestRememberLockExpired(t *testing.T) {
+	c := cache.NewMemoryCache(0, 0, 0)
+	loader := cache.NewLoader(c, 50*time.Millisecond)
+	_, err := loader.Remember("remember-expired", time.Minute, func() (any, error) {
+		// lock expired and acquired by other process while loading
+		time.Sleep(100 * time.Millisecond)
+		if err := c.Put("remember-expired-lock", "other", time.Minute); err != nil {
+			return nil, err
+		}
+		return "kim", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := c.Get("remember-expired-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "other" {
+		t.Fatalf("lock of other process removed, got %v", v)
+	}
+}
+
+func TestRememberType(t *testing.T) {
+	for _, c := range []cache.Cache{
+		cache.NewMemoryCache(0, 0, 0),
+		cache.NewMemoryCache(0, 0, 0, cache.WithSerializer(cache.JSONSerializer())),
+	} {
+		loader := cache.NewLoader(c, 0)
+		for _, value := range []any{42, typedProduct{Name: "pen", Tags: []string{"blue"}}} {
+			load := func() (any, error) {
+				return value, nil
+			}
+
+			missed, err := loader.Remember("remember-type", time.Minute, load)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hit, err := loader.Remember("remember-type", time.Minute, load)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(missed, hit) {
+				t.Fatalf("miss and hit returned different values %#v, %#v", missed, hit)
+			}
+
+			if err := c.Forget("remember-type"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
@@ -7,27 +7,122 @@ import (
 )
 
 // NewRedisCache create a new redis cache manager instance
-func NewRedisCache(prefix string, opt redis.Options) Cache {
+//
+// use WithSerializer option to change values serializer and WithCompression option to compress large values.
+// "-" and "%" characters of prefix escaped in redis keys, so flush and scan of prefix never match keys of other prefixes
+func NewRedisCache(prefix string, opt redis.Options, options ...Option) Cache {
 	rc := new(rCache)
-	rc.init(prefix, opt)
+	rc.init(prefix, opt, newConfig(options))
 	return rc
 }
 
 // NewFileCache create a new file cache manager instance
-func NewFileCache(prefix string, dir string) Cache {
+//
+// use WithSweepInterval option to remove expired items in background, WithSerializer option to change values serializer and WithCompression option to compress large values
+func NewFileCache(prefix string, dir string, options ...Option) Cache {
 	fc := new(fCache)
-	fc.init(prefix, dir)
+	fc.init(prefix, dir, newConfig(options))
 	return fc
 }
 
+// NewMemoryCache create a new in-memory cache manager instance
+//
+// least recently used items evicted when cache exceeds maxEntries items or maxBytes encoded size,
+// pass 0 for unlimited size. expired items removed every cleanupInterval, pass 0 to disable background cleanup.
+// use WithSerializer option to change values serializer and WithCompression option to compress large values
+func NewMemoryCache(maxEntries uint, maxBytes uint64, cleanupInterval time.Duration, options ...Option) Cache {
+	mc := new(mCache)
+	mc.init(maxEntries, maxBytes, cleanupInterval, newConfig(options))
+	return mc
+}
+
+// NewTieredCache create a new two-tier cache manager instance
+//
+// items read from local cache first and fall back to remote cache, remote items back-filled to local cache
+// with ttl of at most localTTL and remote ttl. writes applied to both caches and numeric operations run on remote cache only.
+// items not cached locally if localTTL is not positive
+func NewTieredCache(local Cache, remote Cache, localTTL time.Duration) Cache {
+	tc := new(tCache)
+	tc.init(local, remote, localTTL)
+	return tc
+}
+
+// NewEncryptedCache create a new cache wrapper that encrypt values with AES-GCM before storing them in cache
+//
+// keys map key ids to 16, 24 or 32 bytes AES keys. values encrypted with keyID key and key id stored
+// with encrypted value, so values of all keys decrypted and keys can rotated by changing keyID.
+// values encoded with WithSerializer and WithCompression options before encryption.
+// numeric operations run with compare and swap of underlying cache. values not encrypted by wrapper
+// rejected with ErrNotEncrypted, use WithPlaintextFallback option to read them as is while migrating to encryption
+func NewEncryptedCache(cache Cache, keyID string, keys map[string][]byte, options ...Option) (Cache, error) {
+	ec := new(eCache)
+	if err := ec.init(cache, keyID, keys, newConfig(options)); err != nil {
+		return nil, err
+	}
+	return ec, nil
+}
+
+// NewLoader create a new compute-on-miss loader for cache
+//
+// concurrent misses for same key in current process run loader once. pass non-zero lockTTL to
+// hold a lock item in cache while loading, so only one process run loader for same key.
+// loaded values returned as decoded from cache, so hits and misses return same type
+func NewLoader(cache Cache, lockTTL time.Duration) Loader {
+	ld := new(lDriver)
+	ld.init(cache, lockTTL)
+	return ld
+}
+
+// NewTagger create a new tag based invalidation helper for cache
+//
+// tagged items stored under key namespace of their tags versions and flushing a tag change its version,
+// so invalidated items never read again and removed by cache expiration.
+// lockTTL used for Remember methods loader, see NewLoader
+func NewTagger(cache Cache, lockTTL time.Duration) Tagger {
+	tg := new(tgDriver)
+	tg.init(cache, lockTTL)
+	return tg
+}
+
+// NewTyped create a new generic cache wrapper with typed values
+//
+// values decoded with cache driver serializer, so structs and slices returned as T.
+// lockTTL used for Remember methods loader, see NewLoader
+func NewTyped[T any](cache Cache, lockTTL time.Duration) Typed[T] {
+	td := new(tpDriver[T])
+	td.init(cache, lockTTL)
+	return td
+}
+
 // NewRateLimiter create a new rate limiter
-func NewRateLimiter(key string, maxAttempts uint32, ttl time.Duration, cache Cache) (RateLimiter, error) {
-	limiter := new(rLimiter)
-	if err := limiter.init(key, maxAttempts, ttl, cache); err != nil {
+//
+// limiter allow maxAttempts attempts in ttl window, use WithSlidingWindow option to count attempts with sliding window,
+// WithTokenBucket option to refill maxAttempts attempts every ttl with bursts and WithGCRA option to space attempts evenly
+func NewRateLimiter(key string, maxAttempts uint32, ttl time.Duration, cache Cache, options ...LimiterOption) (RateLimiter, error) {
+	return newLimiter(key, maxAttempts, ttl, cache, newLimiterConfig(options), false)
+}
+
+// NewKeyedRateLimiter create a new reusable rate limiter factory for per identity limits (e.g. per user or ip)
+//
+// limiters of identities stored under prefix and created on first hit, see NewRateLimiter for options
+func NewKeyedRateLimiter(prefix string, maxAttempts uint32, ttl time.Duration, cache Cache, options ...LimiterOption) (KeyedRateLimiter, error) {
+	kl := new(kLimiter)
+	if err := kl.init(prefix, maxAttempts, ttl, cache, newLimiterConfig(options)); err != nil {
+		return nil, err
+	}
+	return kl, nil
+}
+
+// NewGCRALimiter create a new generic cell rate algorithm rate limiter
+//
+// limiter allow maxAttempts attempts every ttl spaced evenly with bursts of at most burst attempts (pass 0 for maxAttempts).
+// limiter store one theoretical arrival time item per key and created on first hit
+func NewGCRALimiter(key string, maxAttempts uint32, ttl time.Duration, burst uint32, cache Cache) (GCRALimiter, error) {
+	limiter := new(gcraLimiter)
+	if err := limiter.init(key, maxAttempts, ttl, burst, cache); err != nil {
 		return nil, err
-	} else {
-		return limiter, nil
 	}
+	return limiter, nil
 }
 
 // NewVerificationCode create a new verification code manager instance
@@ -0,0 +1,108 @@
+package cache
+
+import "time"
+
+// Option configure cache driver
+type Option func(*config)
+
+// LimiterOption configure rate limiter
+type LimiterOption func(*limiterConfig)
+
+// rate limiter algorithms
+type limiterAlgorithm int
+
+const (
+	fixedWindow limiterAlgorithm = iota
+	slidingWindow
+	tokenBucket
+	gcra
+)
+
+type limiterConfig struct {
+	algorithm limiterAlgorithm
+	burst     uint32
+}
+
+func newLimiterConfig(options []LimiterOption) limiterConfig {
+	conf := limiterConfig{algorithm: fixedWindow}
+	for _, option := range options {
+		option(&conf)
+	}
+	return conf
+}
+
+// WithSlidingWindow count attempts of rate limiter with sliding window counter instead of fixed window,
+// attempts of previous window weighted by its remaining overlap with sliding window
+func WithSlidingWindow() LimiterOption {
+	return func(conf *limiterConfig) {
+		conf.algorithm = slidingWindow
+	}
+}
+
+// WithTokenBucket limit attempts with token bucket, bucket refilled with max attempts tokens every ttl
+// and hold at most burst tokens. burst of 0 means max attempts
+func WithTokenBucket(burst uint32) LimiterOption {
+	return func(conf *limiterConfig) {
+		conf.algorithm = tokenBucket
+		conf.burst = burst
+	}
+}
+
+// WithGCRA limit attempts with generic cell rate algorithm, see NewGCRALimiter
+func WithGCRA(burst uint32) LimiterOption {
+	return func(conf *limiterConfig) {
+		conf.algorithm = gcra
+		conf.burst = burst
+	}
+}
+
+type config struct {
+	sweepInterval     time.Duration
+	serializer        Serializer
+	compressor        Compressor
+	compressThreshold int
+	plaintext         bool
+}
+
+func newConfig(options []Option) config {
+	conf := config{
+		serializer: GobSerializer(),
+	}
+	for _, option := range options {
+		option(&conf)
+	}
+	return conf
+}
+
+// WithSweepInterval remove expired items of file driver in background every interval
+func WithSweepInterval(interval time.Duration) Option {
+	return func(conf *config) {
+		conf.sweepInterval = interval
+	}
+}
+
+// WithSerializer set serializer of cache values, gob serializer used by default
+func WithSerializer(serializer Serializer) Option {
+	return func(conf *config) {
+		if serializer != nil {
+			conf.serializer = serializer
+		}
+	}
+}
+
+// WithCompression compress serialized values with encoded size of at least threshold bytes.
+// compressed and uncompressed values can coexist, numeric values never compressed
+func WithCompression(compressor Compressor, threshold int) Option {
+	return func(conf *config) {
+		conf.compressor = compressor
+		conf.compressThreshold = threshold
+	}
+}
+
+// WithPlaintextFallback return values not encrypted by encrypted cache wrapper (e.g. written before enabling encryption) as is,
+// use only while migrating existing cache to encryption, unencrypted values rejected with ErrNotEncrypted by default
+func WithPlaintextFallback() Option {
+	return func(conf *config) {
+		conf.plaintext = true
+	}
+}
@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bopher/utils"
+	"github.com/go-redis/redis/v8"
+)
+
+// bucketTakeScript refill bucket state and take tokens and return taken flag and tokens left.
+// if check passed tokens taken only if bucket has enough tokens, invalid state treated as full bucket
+var bucketTakeScript = redis.NewScript(luaValues + `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local tokens = capacity
+local _, state = split(redis.call("GET", KEYS[1]))
+if state then
+	local stored, at = string.match(state, "^([^:]+):(%d+)$")
+	if stored and tonumber(stored) then
+		tokens = math.min(capacity, tonumber(stored) + math.max(0, now - tonumber(at)) * rate)
+	end
+end
+local cost = tonumber(ARGV[4])
+if ARGV[6] == "1" and tokens < cost then
+	return {0, string.format("%.17g", tokens)}
+end
+tokens = math.max(0, tokens - cost)
+local res = string.format("%.17g", tokens)
+redis.call("SET", KEYS[1], with_lifetime(ARGV[5], res .. ":" .. ARGV[3]), "PX", ARGV[5])
+return {1, res}`)
+
+type tbLimiter struct {
+	key   string
+	max   uint32
+	ttl   time.Duration
+	burst uint32
+	cache Cache
+}
+
+func (tb tbLimiter) err(pattern string, params ...any) error {
+	return utils.TaggedError([]string{"RateLimiter", tb.key}, pattern, params...)
+}
+
+func (tb *tbLimiter) init(key string, maxAttempts uint32, ttl time.Duration, burst uint32, cache Cache) error {
+	tb.key = key
+	tb.max = maxAttempts
+	tb.ttl = ttl
+	tb.burst = burst
+	tb.cache = cache
+
+	if burst == 0 {
+		tb.burst = maxAttempts
+	}
+
+	if maxAttempts == 0 || ttl <= 0 {
+		return tb.err("token bucket refill rate must be positive")
+	}
+	return nil
+}
+
+// rate get refilled tokens per microsecond
+func (tb tbLimiter) rate() float64 {
+	return float64(tb.max) / (float64(tb.ttl) / float64(time.Microsecond))
+}
+
+// stateTTL get refill time of empty bucket, state of full bucket not needed
+func (tb tbLimiter) stateTTL() time.Duration {
+	return time.Duration(math.Ceil(float64(tb.burst) / float64(tb.max) * float64(tb.ttl)))
+}
+
+// refill get tokens of stored state at now (unix microseconds), missing and invalid state treated as full bucket
+func (tb tbLimiter) refill(stored any, now int64) float64 {
+	str, _ := stored.(string)
+	tokensStr, atStr, ok := strings.Cut(str, ":")
+	if !ok {
+		return float64(tb.burst)
+	}
+
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return float64(tb.burst)
+	}
+
+	at, err := strconv.ParseInt(atStr, 10, 64)
+	if err != nil {
+		return float64(tb.burst)
+	}
+
+	if elapsed := now - at; elapsed > 0 {
+		tokens += float64(elapsed) * tb.rate()
+	}
+	return math.Min(float64(tb.burst), tokens)
+}
+
+// tokens get current tokens of bucket
+func (tb tbLimiter) tokens() (float64, error) {
+	stored, err := tb.cache.Get(tb.key)
+	if err != nil {
+		return 0, tb.err("%w", err)
+	}
+	return tb.refill(stored, time.Now().UnixMicro()), nil
+}
+
+// take refill bucket and take cost tokens and return taken flag and tokens left. if check passed tokens taken
+// only if bucket has enough tokens. redis driver run one atomic script and other drivers replace state
+// with compare and swap (retried on conflict)
+func (tb tbLimiter) take(cost float64, check bool) (bool, float64, error) {
+	if s, ok := tb.cache.(scripter); ok {
+		flag := 0
+		if check {
+			flag = 1
+		}
+
+		res, err := s.runScript(
+			bucketTakeScript,
+			[]string{tb.key},
+			tb.burst, tb.rate(), time.Now().UnixMicro(), cost, ttlMillis(tb.stateTTL()), flag,
+		)
+		if err != nil {
+			return false, 0, tb.err("%w", err)
+		}
+
+		values, _ := res.([]any)
+		if len(values) != 2 {
+			return false, 0, tb.err("invalid take script result %v", res)
+		}
+
+		taken, _ := values[0].(int64)
+		str, _ := values[1].(string)
+		if tokens, err := strconv.ParseFloat(str, 64); err != nil {
+			return false, 0, tb.err("%w", err)
+		} else {
+			return taken == 1, tokens, nil
+		}
+	}
+
+	for {
+		stored, err := tb.cache.Get(tb.key)
+		if err != nil {
+			return false, 0, tb.err("%w", err)
+		}
+
+		now := time.Now().UnixMicro()
+		tokens := tb.refill(stored, now)
+		if check && tokens < cost {
+			return false, tokens, nil
+		}
+
+		tokens = math.Max(0, tokens-cost)
+		state := strconv.FormatFloat(tokens, 'g', -1, 64) + ":" + strconv.FormatInt(now, 10)
+
+		var ok bool
+		if stored == nil {
+			ok, err = tb.cache.Add(tb.key, state, tb.stateTTL())
+		} else if ok, err = tb.cache.CompareAndSwap(tb.key, stored, state); ok && err == nil {
+			_, err = tb.cache.Expire(tb.key, tb.stateTTL())
+		}
+
+		if err != nil {
+			return false, 0, tb.err("%w", err)
+		}
+
+		if ok {
+			return true, tokens, nil
+		}
+	}
+}
+
+// wait get time until bucket with tokens refilled to target tokens
+func (tb tbLimiter) wait(tokens float64, target float64) time.Duration {
+	if tokens >= target {
+		return 0
+	}
+	return time.Duration(math.Ceil((target-tokens)/tb.rate())) * time.Microsecond
+}
+
+// Hit take one token from bucket
+func (tb tbLimiter) Hit() error {
+	_, _, err := tb.take(1, false)
+	return err
+}
+
+// Lock take all tokens of bucket
+func (tb tbLimiter) Lock() error {
+	_, _, err := tb.take(float64(tb.burst), false)
+	return err
+}
+
+// Reset fill bucket
+func (tb tbLimiter) Reset() error {
+	if err := tb.cache.Forget(tb.key); err != nil {
+		return tb.err("%w", err)
+	}
+	return nil
+}
+
+func (tb tbLimiter) Clear() error {
+	return tb.Reset()
+}
+
+func (tb tbLimiter) MustLock() (bool, error) {
+	tokens, err := tb.tokens()
+	if err != nil {
+		return true, err
+	}
+	return tokens < 1, nil
+}
+
+// TotalAttempts get taken tokens of bucket
+func (tb tbLimiter) TotalAttempts() (uint32, error) {
+	tokens, err := tb.tokens()
+	if err != nil {
+		return tb.burst, err
+	}
+	return tb.burst - uint32(tokens), nil
+}
+
+// RetriesLeft get whole tokens of bucket
+func (tb tbLimiter) RetriesLeft() (uint32, error) {
+	tokens, err := tb.tokens()
+	if err != nil {
+		return 0, err
+	}
+	return uint32(tokens), nil
+}
+
+// AvailableIn get time until next token refilled, returns 0 if bucket has token
+func (tb tbLimiter) AvailableIn() (time.Duration, error) {
+	tokens, err := tb.tokens()
+	if err != nil {
+		return 0, err
+	}
+	return tb.wait(tokens, 1), nil
+}
+
+// Attempt take one token if bucket has token
+func (tb tbLimiter) Attempt() (Result, error) {
+	taken, tokens, err := tb.take(1, true)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:     taken,
+		RetriesLeft: uint32(tokens),
+		RetryAfter:  tb.wait(tokens, 1),
+		ResetAfter:  tb.wait(tokens, float64(tb.burst)),
+	}, nil
+}
+
+func (tb tbLimiter) WithContext(ctx context.Context) RateLimiter {
+	tb.cache = tb.cache.WithContext(ctx)
+	return &tb
+}
@@ -1,15 +1,38 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/bopher/utils"
+	"github.com/go-redis/redis/v8"
 )
 
+// fixedAttemptScript decrement retries left if not locked and return allowed flag, retries left and ttl in milliseconds.
+// allowed flag is -1 for missing and -2 for non numeric record
+var fixedAttemptScript = redis.NewScript(luaValues + `
+local value = redis.call("GET", KEYS[1])
+if not value then
+	return {-1, 0, 0}
+end
+local left = number(value)
+if not left then
+	return {-2, 0, 0}
+end
+local ttl = redis.call("PTTL", KEYS[1])
+if left <= 0 then
+	return {0, 0, ttl}
+end
+local header = split(value)
+redis.call("SET", KEYS[1], header .. marked(left - 1), "KEEPTTL")
+return {1, left - 1, ttl}`)
+
 type rLimiter struct {
 	key   string
 	max   uint32
 	ttl   time.Duration
+	lazy  bool
 	cache Cache
 }
 
@@ -18,56 +41,87 @@ func (rl rLimiter) err(pattern string, params ...any) error {
 }
 
 func (rl rLimiter) notExistsErr() error {
-	return utils.TaggedError([]string{"RateLimiter", "NotExists", rl.key}, "%s not exists", rl.key)
+	return utils.TaggedError([]string{"RateLimiter", "NotExists", rl.key}, "%s not exists: %w", rl.key, ErrNotFound)
 }
 
-func (rl *rLimiter) init(key string, maxAttempts uint32, ttl time.Duration, cache Cache) error {
+// init create limiter, record of lazy limiter created on first hit
+func (rl *rLimiter) init(key string, maxAttempts uint32, ttl time.Duration, cache Cache, lazy bool) error {
 	rl.key = key
 	rl.max = maxAttempts
 	rl.ttl = ttl
+	rl.lazy = lazy
 	rl.cache = cache
 
-	exists, err := cache.Exists(key)
-	if err != nil {
-		return rl.err(err.Error())
+	if lazy {
+		return nil
 	}
 
-	if !exists {
-		return cache.Put(key, maxAttempts, ttl)
+	if _, err := cache.Add(key, maxAttempts, ttl); err != nil {
+		return rl.err("%w", err)
 	}
-
 	return nil
 }
 
-func (rl rLimiter) Hit() error {
-	exists, err := rl.cache.Decrement(rl.key, 1)
-	if err != nil {
-		return rl.err(err.Error())
+// create put missing record of lazy limiter, return false if limiter not lazy
+func (rl rLimiter) create() (bool, error) {
+	if !rl.lazy {
+		return false, nil
+	}
+
+	if _, err := rl.cache.Add(rl.key, rl.max, rl.ttl); err != nil {
+		return false, rl.err("%w", err)
 	}
+	return true, nil
+}
 
-	if !exists {
+// retry create missing record of lazy limiter or return not exists error
+func (rl rLimiter) retry() error {
+	if created, err := rl.create(); err != nil {
+		return err
+	} else if !created {
 		return rl.notExistsErr()
 	}
 	return nil
 }
 
-func (rl rLimiter) Lock() error {
-	exists, err := rl.cache.Set(rl.key, 0)
-	if err != nil {
-		return rl.err(err.Error())
-	}
+func (rl rLimiter) Hit() error {
+	for {
+		exists, err := rl.cache.Decrement(rl.key, 1)
+		if err != nil {
+			return rl.err("%w", err)
+		}
 
-	if !exists {
-		return rl.notExistsErr()
+		if exists {
+			return nil
+		}
+
+		if err := rl.retry(); err != nil {
+			return err
+		}
 	}
+}
 
-	return nil
+func (rl rLimiter) Lock() error {
+	for {
+		exists, err := rl.cache.Set(rl.key, 0)
+		if err != nil {
+			return rl.err("%w", err)
+		}
+
+		if exists {
+			return nil
+		}
+
+		if err := rl.retry(); err != nil {
+			return err
+		}
+	}
 }
 
 func (rl rLimiter) Reset() error {
 	err := rl.cache.Put(rl.key, rl.max, rl.ttl)
 	if err != nil {
-		return rl.err(err.Error())
+		return rl.err("%w", err)
 	}
 
 	return nil
@@ -76,7 +130,7 @@ func (rl rLimiter) Reset() error {
 func (rl rLimiter) Clear() error {
 	err := rl.cache.Forget(rl.key)
 	if err != nil {
-		return rl.err(err.Error())
+		return rl.err("%w", err)
 	}
 
 	return nil
@@ -85,7 +139,7 @@ func (rl rLimiter) Clear() error {
 func (rl rLimiter) MustLock() (bool, error) {
 	caster, err := rl.cache.Cast(rl.key)
 	if err != nil {
-		return true, rl.err(err.Error())
+		return true, rl.err("%w", err)
 	}
 
 	if caster.IsNil() {
@@ -94,7 +148,7 @@ func (rl rLimiter) MustLock() (bool, error) {
 
 	v, err := caster.Int()
 	if err != nil {
-		err = rl.err(err.Error())
+		err = rl.err("%w", err)
 	}
 	return v <= 0, err
 }
@@ -102,16 +156,18 @@ func (rl rLimiter) MustLock() (bool, error) {
 func (rl rLimiter) TotalAttempts() (uint32, error) {
 	caster, err := rl.cache.Cast(rl.key)
 	if err != nil {
-		return rl.max, rl.err(err.Error())
+		return rl.max, rl.err("%w", err)
 	}
 
-	if caster.IsNil() {
+	if caster.IsNil() && rl.lazy {
+		return 0, nil
+	} else if caster.IsNil() {
 		return rl.max, nil
 	}
 
 	v, err := caster.Int()
 	if err != nil {
-		return rl.max, rl.err(err.Error())
+		return rl.max, rl.err("%w", err)
 	}
 
 	if v > int(rl.max) {
@@ -124,16 +180,18 @@ func (rl rLimiter) TotalAttempts() (uint32, error) {
 func (rl rLimiter) RetriesLeft() (uint32, error) {
 	caster, err := rl.cache.Cast(rl.key)
 	if err != nil {
-		return 0, rl.err(err.Error())
+		return 0, rl.err("%w", err)
 	}
 
-	if caster.IsNil() {
+	if caster.IsNil() && rl.lazy {
+		return rl.max, nil
+	} else if caster.IsNil() {
 		return 0, nil
 	}
 
 	v, err := caster.Int()
 	if err != nil {
-		err = rl.err(err.Error())
+		err = rl.err("%w", err)
 	}
 	if v < 0 {
 		v = 0
@@ -142,9 +200,137 @@ func (rl rLimiter) RetriesLeft() (uint32, error) {
 }
 
 func (rl rLimiter) AvailableIn() (time.Duration, error) {
-	if v, err := rl.cache.TTL(rl.key); err != nil {
-		return 0, rl.err(err.Error())
+	if v, err := rl.cache.TTL(rl.key); errors.Is(err, ErrNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, rl.err("%w", err)
 	} else {
 		return v, nil
 	}
 }
+
+// result get attempt result of retries left and window ttl
+func (rl rLimiter) result(allowed bool, left int64, ttl time.Duration) Result {
+	res := Result{Allowed: allowed, ResetAfter: ttl}
+	if left > 0 {
+		res.RetriesLeft = uint32(left)
+	} else {
+		res.RetryAfter = ttl
+	}
+	return res
+}
+
+// Attempt decrement retries left if not locked
+func (rl rLimiter) Attempt() (Result, error) {
+	for {
+		res, exists, err := rl.attempt()
+		if err != nil || exists {
+			return res, err
+		}
+
+		if err := rl.retry(); err != nil {
+			return Result{}, err
+		}
+	}
+}
+
+// attempt decrement retries left if not locked and return false if record not exists. redis driver run one atomic script
+// and other drivers replace retries left with compare and swap (retried on conflict)
+func (rl rLimiter) attempt() (Result, bool, error) {
+	if s, ok := rl.cache.(scripter); ok {
+		res, err := s.runScript(fixedAttemptScript, []string{rl.key})
+		if err != nil {
+			return Result{}, false, rl.err("%w", err)
+		}
+
+		values, _ := res.([]any)
+		if len(values) != 3 {
+			return Result{}, false, rl.err("invalid attempt script result %v", res)
+		}
+
+		flag, _ := values[0].(int64)
+		left, _ := values[1].(int64)
+		ms, _ := values[2].(int64)
+		switch flag {
+		case -1:
+			return Result{}, false, nil
+		case -2:
+			return Result{}, false, rl.err("%s: %w", rl.key, ErrNotNumeric)
+		}
+
+		ttl := NoExpiration
+		if ms >= 0 {
+			ttl = time.Duration(ms) * time.Millisecond
+		}
+		return rl.result(flag == 1, left, ttl), true, nil
+	}
+
+	for {
+		v, err := rl.cache.Get(rl.key)
+		if err != nil {
+			return Result{}, false, rl.err("%w", err)
+		}
+
+		if v == nil {
+			return Result{}, false, nil
+		}
+
+		left, ok := toInt64(v)
+		if !ok {
+			return Result{}, false, rl.err("%s: %w", rl.key, ErrNotNumeric)
+		}
+
+		allowed := left > 0
+		if allowed {
+			if swapped, err := rl.cache.CompareAndSwap(rl.key, left, left-1); err != nil {
+				return Result{}, false, rl.err("%w", err)
+			} else if !swapped {
+				continue
+			}
+			left--
+		}
+
+		ttl, err := rl.cache.TTL(rl.key)
+		if errors.Is(err, ErrNotFound) {
+			ttl = 0
+		} else if err != nil {
+			return Result{}, false, rl.err("%w", err)
+		}
+		return rl.result(allowed, left, ttl), true, nil
+	}
+}
+
+func (rl rLimiter) WithContext(ctx context.Context) RateLimiter {
+	rl.cache = rl.cache.WithContext(ctx)
+	return &rl
+}
+
+// newLimiter create rate limiter of configured algorithm, fixed window record of lazy limiter created on first hit
+func newLimiter(key string, maxAttempts uint32, ttl time.Duration, cache Cache, conf limiterConfig, lazy bool) (RateLimiter, error) {
+	switch conf.algorithm {
+	case slidingWindow:
+		limiter := new(swLimiter)
+		if err := limiter.init(key, maxAttempts, ttl, cache); err != nil {
+			return nil, err
+		}
+		return limiter, nil
+	case tokenBucket:
+		limiter := new(tbLimiter)
+		if err := limiter.init(key, maxAttempts, ttl, conf.burst, cache); err != nil {
+			return nil, err
+		}
+		return limiter, nil
+	case gcra:
+		limiter := new(gcraRateLimiter)
+		if err := limiter.init(key, maxAttempts, ttl, conf.burst, cache); err != nil {
+			return nil, err
+		}
+		return limiter, nil
+	}
+
+	limiter := new(rLimiter)
+	if err := limiter.init(key, maxAttempts, ttl, cache, lazy); err != nil {
+		return nil, err
+	}
+	return limiter, nil
+}
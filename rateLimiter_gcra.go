@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/bopher/utils"
+	"github.com/go-redis/redis/v8"
+)
+
+// gcraScript advance theoretical arrival time (unix microseconds) by interval, if check passed attempt
+// counted only when allowed. returns allowed flag and new or current theoretical arrival time
+var gcraScript = redis.NewScript(luaValues + `
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local tolerance = tonumber(ARGV[3])
+local tat = number(redis.call("GET", KEYS[1])) or 0
+tat = math.max(tat, now)
+local new_tat = tat + interval
+if ARGV[4] == "1" and new_tat - tolerance > now then
+	return {0, string.format("%.0f", tat)}
+end
+local ttl = string.format("%.0f", math.ceil((new_tat - now) / 1000))
+redis.call("SET", KEYS[1], with_lifetime(ttl, marked(new_tat)), "PX", ttl)
+return {1, string.format("%.0f", new_tat)}`)
+
+type gcraLimiter struct {
+	key       string
+	burst     uint32
+	interval  int64
+	tolerance int64
+	cache     Cache
+}
+
+func (gl gcraLimiter) err(pattern string, params ...any) error {
+	return utils.TaggedError([]string{"RateLimiter", gl.key}, pattern, params...)
+}
+
+func (gl *gcraLimiter) init(key string, maxAttempts uint32, ttl time.Duration, burst uint32, cache Cache) error {
+	gl.key = key
+	gl.burst = burst
+	gl.cache = cache
+
+	if burst == 0 {
+		gl.burst = maxAttempts
+	}
+
+	if maxAttempts == 0 || ttl <= 0 {
+		return gl.err("gcra rate must be positive")
+	}
+
+	gl.interval = (ttl / time.Duration(maxAttempts)).Microseconds()
+	gl.tolerance = gl.interval * int64(gl.burst)
+	if gl.interval <= 0 {
+		return gl.err("gcra emission interval must be at least one microsecond")
+	}
+	return nil
+}
+
+// micros convert microseconds to duration
+func micros(v int64) time.Duration {
+	return time.Duration(v) * time.Microsecond
+}
+
+// tat get theoretical arrival time of limiter and now in unix microseconds, past and missing arrival times are now
+func (gl gcraLimiter) tat() (int64, int64, error) {
+	stored, err := gl.cache.Get(gl.key)
+	if err != nil {
+		return 0, 0, gl.err("%w", err)
+	}
+
+	now := time.Now().UnixMicro()
+	tat, _ := toInt64(stored)
+	if tat < now {
+		tat = now
+	}
+	return tat, now, nil
+}
+
+// update advance theoretical arrival time by interval and return allowed flag, new or current theoretical arrival time and now.
+// if check passed attempt counted only when allowed. redis driver run one atomic script
+// and other drivers replace arrival time with compare and swap (retried on conflict)
+func (gl gcraLimiter) update(check bool) (bool, int64, int64, error) {
+	if s, ok := gl.cache.(scripter); ok {
+		now := time.Now().UnixMicro()
+		flag := 0
+		if check {
+			flag = 1
+		}
+
+		res, err := s.runScript(gcraScript, []string{gl.key}, now, gl.interval, gl.tolerance, flag)
+		if err != nil {
+			return false, 0, now, gl.err("%w", err)
+		}
+
+		values, _ := res.([]any)
+		if len(values) != 2 {
+			return false, 0, now, gl.err("invalid gcra script result %v", res)
+		}
+
+		allowed, _ := values[0].(int64)
+		str, _ := values[1].(string)
+		tat, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return false, 0, now, gl.err("invalid gcra script result %v", res)
+		}
+		return allowed == 1, tat, now, nil
+	}
+
+	for {
+		stored, err := gl.cache.Get(gl.key)
+		if err != nil {
+			return false, 0, 0, gl.err("%w", err)
+		}
+
+		now := time.Now().UnixMicro()
+		old, numeric := toInt64(stored)
+		if stored != nil && !numeric {
+			return false, 0, 0, gl.err("%s: %w", gl.key, ErrNotNumeric)
+		}
+
+		tat := old
+		if tat < now {
+			tat = now
+		}
+
+		next := tat + gl.interval
+		if check && next-gl.tolerance > now {
+			return false, tat, now, nil
+		}
+
+		var ok bool
+		if stored == nil {
+			ok, err = gl.cache.Add(gl.key, next, micros(next-now))
+		} else if ok, err = gl.cache.CompareAndSwap(gl.key, old, next); ok && err == nil {
+			_, err = gl.cache.Expire(gl.key, micros(next-now))
+		}
+
+		if err != nil {
+			return false, 0, 0, gl.err("%w", err)
+		}
+
+		if ok {
+			return true, next, now, nil
+		}
+	}
+}
+
+// retriesLeft get attempts allowed at now for theoretical arrival time
+func (gl gcraLimiter) retriesLeft(tat int64, now int64) uint32 {
+	left := (gl.tolerance - (tat - now)) / gl.interval
+	if left < 0 {
+		return 0
+	}
+	return uint32(left)
+}
+
+// wait get time until next attempt allowed at now for theoretical arrival time
+func (gl gcraLimiter) wait(tat int64, now int64) time.Duration {
+	if wait := tat + gl.interval - gl.tolerance - now; wait > 0 {
+		return micros(wait)
+	}
+	return 0
+}
+
+func (gl gcraLimiter) Allow() (bool, time.Duration, time.Duration, error) {
+	allowed, tat, now, err := gl.update(true)
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	if !allowed {
+		return false, gl.wait(tat, now), micros(tat - now), nil
+	}
+	return true, 0, micros(tat - now), nil
+}
+
+func (gl gcraLimiter) Attempt() (Result, error) {
+	allowed, tat, now, err := gl.update(true)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:     allowed,
+		RetriesLeft: gl.retriesLeft(tat, now),
+		RetryAfter:  gl.wait(tat, now),
+		ResetAfter:  micros(tat - now),
+	}, nil
+}
+
+// Hit count attempt even if not allowed
+func (gl gcraLimiter) Hit() error {
+	_, _, _, err := gl.update(false)
+	return err
+}
+
+// Lock move theoretical arrival time to end of burst tolerance
+func (gl gcraLimiter) Lock() error {
+	now := time.Now().UnixMicro()
+	if err := gl.cache.Put(gl.key, now+gl.tolerance, micros(gl.tolerance)); err != nil {
+		return gl.err("%w", err)
+	}
+	return nil
+}
+
+func (gl gcraLimiter) Reset() error {
+	if err := gl.cache.Forget(gl.key); err != nil {
+		return gl.err("%w", err)
+	}
+	return nil
+}
+
+func (gl gcraLimiter) Clear() error {
+	return gl.Reset()
+}
+
+func (gl gcraLimiter) MustLock() (bool, error) {
+	tat, now, err := gl.tat()
+	if err != nil {
+		return true, err
+	}
+	return tat+gl.interval-gl.tolerance > now, nil
+}
+
+func (gl gcraLimiter) TotalAttempts() (uint32, error) {
+	left, err := gl.RetriesLeft()
+	return gl.burst - left, err
+}
+
+// RetriesLeft get attempts allowed now
+func (gl gcraLimiter) RetriesLeft() (uint32, error) {
+	tat, now, err := gl.tat()
+	if err != nil {
+		return 0, err
+	}
+	return gl.retriesLeft(tat, now), nil
+}
+
+// AvailableIn get time until next attempt allowed, returns 0 if attempt allowed
+func (gl gcraLimiter) AvailableIn() (time.Duration, error) {
+	tat, now, err := gl.tat()
+	if err != nil {
+		return 0, err
+	}
+	return gl.wait(tat, now), nil
+}
+
+func (gl gcraLimiter) WithContext(ctx context.Context) GCRALimiter {
+	gl.cache = gl.cache.WithContext(ctx)
+	return &gl
+}
+
+// gcraRateLimiter gcra limiter with WithContext of RateLimiter, created by NewRateLimiter with WithGCRA option
+type gcraRateLimiter struct {
+	gcraLimiter
+}
+
+func (gr gcraRateLimiter) WithContext(ctx context.Context) RateLimiter {
+	gr.cache = gr.cache.WithContext(ctx)
+	return &gr
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+type kLimiter struct {
+	prefix string
+	max    uint32
+	ttl    time.Duration
+	conf   limiterConfig
+	cache  Cache
+}
+
+func (kl *kLimiter) init(prefix string, maxAttempts uint32, ttl time.Duration, cache Cache, conf limiterConfig) error {
+	kl.prefix = prefix
+	kl.max = maxAttempts
+	kl.ttl = ttl
+	kl.conf = conf
+	kl.cache = cache
+
+	// validate options once, limiters of identities never touch cache on create
+	_, err := newLimiter(prefix, maxAttempts, ttl, cache, conf, true)
+	return err
+}
+
+func (kl kLimiter) For(identity string) RateLimiter {
+	// options validated on init, so limiter creation never fails
+	limiter, _ := newLimiter(kl.prefix+":"+identity, kl.max, kl.ttl, kl.cache, kl.conf, true)
+	return limiter
+}
+
+func (kl kLimiter) Attempt(identity string) (Result, error) {
+	return kl.For(identity).Attempt()
+}
+
+func (kl kLimiter) WithContext(ctx context.Context) KeyedRateLimiter {
+	kl.cache = kl.cache.WithContext(ctx)
+	return &kl
+}
@@ -0,0 +1,307 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/bopher/utils"
+	"github.com/go-redis/redis/v8"
+)
+
+// slidingHitScript increment counter of window and set its lifetime on create
+var slidingHitScript = redis.NewScript(luaValues + `
+local v = redis.call("GET", KEYS[1])
+local count = (number(v) or 0) + tonumber(ARGV[1])
+if redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("SET", KEYS[1], with_lifetime(ARGV[2], marked(count)), "PX", ARGV[2])
+else
+	redis.call("SET", KEYS[1], split(v) .. marked(count), "KEEPTTL")
+end
+return count`)
+
+// slidingAttemptScript count attempt in current window if weighted attempts below max
+// and return allowed flag and counters of previous and current windows
+var slidingAttemptScript = redis.NewScript(luaValues + `
+local prev = number(redis.call("GET", KEYS[1])) or 0
+local v = redis.call("GET", KEYS[2])
+local curr = number(v) or 0
+if prev * tonumber(ARGV[1]) + curr >= tonumber(ARGV[2]) then
+	return {0, prev, curr}
+end
+curr = curr + 1
+if redis.call("PTTL", KEYS[2]) < 0 then
+	redis.call("SET", KEYS[2], with_lifetime(ARGV[3], marked(curr)), "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[2], split(v) .. marked(curr), "KEEPTTL")
+end
+return {1, prev, curr}`)
+
+// swState attempts counters of sliding window
+type swState struct {
+	prev    int64
+	curr    int64
+	window  int64
+	elapsed time.Duration
+}
+
+// weight get overlap of previous window with sliding window
+func (st swState) weight(ttl time.Duration) float64 {
+	return 1 - float64(st.elapsed)/float64(ttl)
+}
+
+// attempts get previous window attempts weighted by its overlap with sliding window plus current window attempts
+func (st swState) attempts(ttl time.Duration) float64 {
+	return float64(st.prev)*st.weight(ttl) + float64(st.curr)
+}
+
+// retriesLeft get attempts allowed until lock
+func (st swState) retriesLeft(max uint32, ttl time.Duration) uint32 {
+	left := math.Ceil(float64(max) - st.attempts(ttl))
+	if left <= 0 {
+		return 0
+	}
+
+	if left > float64(max) {
+		return max
+	}
+	return uint32(left)
+}
+
+// availableIn get time until weighted attempts drop below max
+func (st swState) availableIn(max uint32, ttl time.Duration) time.Duration {
+	limit := float64(max)
+	if st.attempts(ttl) < limit {
+		return 0
+	}
+
+	if float64(st.curr) < limit {
+		// previous window attempts slide out during current window
+		at := float64(ttl) * (1 - (limit-float64(st.curr))/float64(st.prev))
+		return time.Duration(at) - st.elapsed
+	}
+
+	if st.curr == 0 {
+		return ttl - st.elapsed
+	}
+
+	// current window attempts slide out during next window
+	return ttl - st.elapsed + time.Duration(float64(ttl)*(1-limit/float64(st.curr)))
+}
+
+// result get attempt result of state
+func (st swState) result(allowed bool, max uint32, ttl time.Duration) Result {
+	res := Result{Allowed: allowed, RetriesLeft: st.retriesLeft(max, ttl)}
+	if res.RetriesLeft == 0 {
+		res.RetryAfter = st.availableIn(max, ttl)
+	}
+
+	switch {
+	case st.curr > 0:
+		res.ResetAfter = 2*ttl - st.elapsed
+	case st.prev > 0:
+		res.ResetAfter = ttl - st.elapsed
+	}
+	return res
+}
+
+type swLimiter struct {
+	key   string
+	max   uint32
+	ttl   time.Duration
+	cache Cache
+}
+
+func (sl swLimiter) err(pattern string, params ...any) error {
+	return utils.TaggedError([]string{"RateLimiter", sl.key}, pattern, params...)
+}
+
+func (sl *swLimiter) init(key string, maxAttempts uint32, ttl time.Duration, cache Cache) error {
+	sl.key = key
+	sl.max = maxAttempts
+	sl.ttl = ttl
+	sl.cache = cache
+
+	if ttl <= 0 {
+		return sl.err("sliding window ttl must be positive")
+	}
+	return nil
+}
+
+// window get index and elapsed time of current window
+func (sl swLimiter) window() (int64, time.Duration) {
+	now := time.Now().UnixNano()
+	return now / int64(sl.ttl), time.Duration(now % int64(sl.ttl))
+}
+
+// windowKey get attempts counter key of window, counters expire after next window
+func (sl swLimiter) windowKey(window int64) string {
+	return sl.key + ":" + strconv.FormatInt(window, 10)
+}
+
+// counter get counter value of items, missing counters are zero
+func (sl swLimiter) counter(items map[string]any, key string) (int64, error) {
+	v, ok := items[key]
+	if !ok {
+		return 0, nil
+	}
+
+	if count, ok := toInt64(v); !ok {
+		return 0, sl.err("%s: %w", key, ErrNotNumeric)
+	} else {
+		return count, nil
+	}
+}
+
+// state read counters of current and previous windows
+func (sl swLimiter) state() (swState, error) {
+	window, elapsed := sl.window()
+	prevKey, currKey := sl.windowKey(window-1), sl.windowKey(window)
+	items, err := sl.cache.GetMany([]string{prevKey, currKey})
+	if err != nil {
+		return swState{}, sl.err("%w", err)
+	}
+
+	st := swState{window: window, elapsed: elapsed}
+	if st.prev, err = sl.counter(items, prevKey); err != nil {
+		return st, err
+	}
+
+	if st.curr, err = sl.counter(items, currKey); err != nil {
+		return st, err
+	}
+	return st, nil
+}
+
+// Hit count attempt in current window, redis driver count attempt with one atomic script
+func (sl swLimiter) Hit() error {
+	window, _ := sl.window()
+	key := sl.windowKey(window)
+	if s, ok := sl.cache.(scripter); ok {
+		if _, err := s.runScript(slidingHitScript, []string{key}, 1, ttlMillis(2*sl.ttl)); err != nil {
+			return sl.err("%w", err)
+		}
+		return nil
+	}
+
+	if _, err := sl.cache.Add(key, 0, 2*sl.ttl); err != nil {
+		return sl.err("%w", err)
+	}
+
+	if _, err := sl.cache.Increment(key, 1); err != nil {
+		return sl.err("%w", err)
+	}
+	return nil
+}
+
+// Lock fill current window attempts
+func (sl swLimiter) Lock() error {
+	window, _ := sl.window()
+	if err := sl.cache.Put(sl.windowKey(window), sl.max, 2*sl.ttl); err != nil {
+		return sl.err("%w", err)
+	}
+	return nil
+}
+
+// Reset remove attempts of sliding window
+func (sl swLimiter) Reset() error {
+	window, _ := sl.window()
+	if err := sl.cache.ForgetMany(sl.windowKey(window-1), sl.windowKey(window)); err != nil {
+		return sl.err("%w", err)
+	}
+	return nil
+}
+
+func (sl swLimiter) Clear() error {
+	return sl.Reset()
+}
+
+func (sl swLimiter) MustLock() (bool, error) {
+	st, err := sl.state()
+	if err != nil {
+		return true, err
+	}
+	return st.retriesLeft(sl.max, sl.ttl) == 0, nil
+}
+
+func (sl swLimiter) TotalAttempts() (uint32, error) {
+	st, err := sl.state()
+	if err != nil {
+		return sl.max, err
+	}
+	return sl.max - st.retriesLeft(sl.max, sl.ttl), nil
+}
+
+func (sl swLimiter) RetriesLeft() (uint32, error) {
+	st, err := sl.state()
+	if err != nil {
+		return 0, err
+	}
+	return st.retriesLeft(sl.max, sl.ttl), nil
+}
+
+// AvailableIn get time until attempts of sliding window drop below max attempts, returns 0 if limiter not locked
+func (sl swLimiter) AvailableIn() (time.Duration, error) {
+	st, err := sl.state()
+	if err != nil {
+		return 0, err
+	}
+	return st.availableIn(sl.max, sl.ttl), nil
+}
+
+// Attempt count attempt in current window if weighted attempts below max attempts, redis driver run one atomic script
+// and other drivers replace current window counter with compare and swap (retried on conflict)
+func (sl swLimiter) Attempt() (Result, error) {
+	if s, ok := sl.cache.(scripter); ok {
+		window, elapsed := sl.window()
+		st := swState{window: window, elapsed: elapsed}
+		res, err := s.runScript(
+			slidingAttemptScript,
+			[]string{sl.windowKey(window - 1), sl.windowKey(window)},
+			st.weight(sl.ttl), sl.max, ttlMillis(2*sl.ttl),
+		)
+		if err != nil {
+			return Result{}, sl.err("%w", err)
+		}
+
+		values, _ := res.([]any)
+		if len(values) != 3 {
+			return Result{}, sl.err("invalid attempt script result %v", res)
+		}
+
+		flag, _ := values[0].(int64)
+		st.prev, _ = values[1].(int64)
+		st.curr, _ = values[2].(int64)
+		return st.result(flag == 1, sl.max, sl.ttl), nil
+	}
+
+	for {
+		st, err := sl.state()
+		if err != nil {
+			return Result{}, err
+		}
+
+		if st.attempts(sl.ttl) >= float64(sl.max) {
+			return st.result(false, sl.max, sl.ttl), nil
+		}
+
+		// counter created before swap, so missing and zero counters swapped same
+		key := sl.windowKey(st.window)
+		if _, err := sl.cache.Add(key, 0, 2*sl.ttl); err != nil {
+			return Result{}, sl.err("%w", err)
+		}
+
+		if swapped, err := sl.cache.CompareAndSwap(key, st.curr, st.curr+1); err != nil {
+			return Result{}, sl.err("%w", err)
+		} else if swapped {
+			st.curr++
+			return st.result(true, sl.max, sl.ttl), nil
+		}
+	}
+}
+
+func (sl swLimiter) WithContext(ctx context.Context) RateLimiter {
+	sl.cache = sl.cache.WithContext(ctx)
+	return &sl
+}
@@ -1,6 +1,9 @@
 package cache
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // RateLimiter interface for rate limiter
 type RateLimiter interface {
@@ -20,4 +23,60 @@ type RateLimiter interface {
 	RetriesLeft() (uint32, error)
 	// AvailableIn get time until unlock
 	AvailableIn() (time.Duration, error)
+	// Attempt count attempt atomically if limiter not locked
+	Attempt() (Result, error)
+	// WithContext get a copy of rate limiter that run cache operations with ctx
+	WithContext(ctx context.Context) RateLimiter
+}
+
+// Result result of rate limiter attempt
+type Result struct {
+	// Allowed attempt allowed and counted
+	Allowed bool
+	// RetriesLeft retries left after attempt
+	RetriesLeft uint32
+	// RetryAfter time until next attempt allowed, 0 if retries left
+	RetryAfter time.Duration
+	// ResetAfter time until limiter fully reset
+	ResetAfter time.Duration
+}
+
+// GCRALimiter interface for generic cell rate algorithm rate limiter
+//
+// GCRALimiter has all methods of RateLimiter but its WithContext returns GCRALimiter,
+// use WithGCRA option of NewRateLimiter to create gcra limiter as RateLimiter
+type GCRALimiter interface {
+	// Hit count attempt even if not allowed
+	Hit() error
+	// Lock lock rate limiter
+	Lock() error
+	// Reset reset rate limiter
+	Reset() error
+	// Clear remove rate limiter record
+	Clear() error
+	// MustLock check if rate limiter must lock access
+	MustLock() (bool, error)
+	// TotalAttempts get user attempts count
+	TotalAttempts() (uint32, error)
+	// RetriesLeft get user retries left
+	RetriesLeft() (uint32, error)
+	// AvailableIn get time until unlock
+	AvailableIn() (time.Duration, error)
+	// Attempt count attempt atomically if limiter not locked
+	Attempt() (Result, error)
+	// Allow count attempt if allowed. retryAfter is time until next attempt allowed for denied attempts
+	// and resetAfter is time until limiter fully reset
+	Allow() (allowed bool, retryAfter time.Duration, resetAfter time.Duration, err error)
+	// WithContext get a copy of gcra limiter that run cache operations with ctx
+	WithContext(ctx context.Context) GCRALimiter
+}
+
+// KeyedRateLimiter interface for rate limiter factory of identities
+type KeyedRateLimiter interface {
+	// For get rate limiter of identity, limiter created on first hit
+	For(identity string) RateLimiter
+	// Attempt count attempt of identity atomically if its limiter not locked
+	Attempt(identity string) (Result, error)
+	// WithContext get a copy of keyed rate limiter that run cache operations with ctx
+	WithContext(ctx context.Context) KeyedRateLimiter
 }
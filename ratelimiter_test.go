@@ -1,6 +1,8 @@
 package cache_test
 
 import (
+	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -110,3 +112,407 @@ func TestAvailable(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestSlidingWindow(t *testing.T) {
+	for name, c := range map[string]cache.Cache{"redis": redisCache(), "memory": memoryCache()} {
+		limiter, err := cache.NewRateLimiter("test-sliding", 5, time.Minute, c, cache.WithSlidingWindow())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = limiter.Reset()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 4; i++ {
+			if err := limiter.Hit(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		mustLock, err := limiter.MustLock()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		remains, err := limiter.RetriesLeft()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if mustLock || remains != 1 {
+			t.Fatalf("%s: failed sliding hit, remains %d", name, remains)
+		}
+
+		err = limiter.Lock()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		total, err := limiter.TotalAttempts()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ttl, err := limiter.AvailableIn()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if total != 5 || ttl <= 0 || ttl > 2*time.Minute {
+			t.Fatalf("%s: failed sliding lock, total %d available in %v", name, total, ttl)
+		}
+	}
+}
+
+func TestSlidingWindowWeight(t *testing.T) {
+	window := 400 * time.Millisecond
+	limiter, err := cache.NewRateLimiter("test-sliding-weight", 4, window, cache.NewMemoryCache(0, 0, 0), cache.WithSlidingWindow())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// start at beginning of window
+	time.Sleep(window - time.Duration(time.Now().UnixNano()%int64(window)) + 5*time.Millisecond)
+	for i := 0; i < 4; i++ {
+		if err := limiter.Hit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mustLock, err := limiter.MustLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !mustLock {
+		t.Fatal("failed sliding lock")
+	}
+
+	// previous window attempts still counted at beginning of next window
+	time.Sleep(window)
+	remains, err := limiter.RetriesLeft()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if remains != 1 {
+		t.Fatalf("failed previous window weight, remains %d", remains)
+	}
+
+	time.Sleep(window)
+	remains, err = limiter.RetriesLeft()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if remains != 4 {
+		t.Fatalf("failed window slide, remains %d", remains)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	for name, c := range map[string]cache.Cache{"redis": redisCache(), "memory": memoryCache(), "file": cache.NewFileCache("bucket", t.TempDir())} {
+		// 10 tokens per second with bursts of 50
+		limiter, err := cache.NewRateLimiter("test-bucket", 10, time.Second, c, cache.WithTokenBucket(50))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = limiter.Reset()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 30; i++ {
+			if err := limiter.Hit(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		remains, err := limiter.RetriesLeft()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		total, err := limiter.TotalAttempts()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if remains < 20 || remains > 21 || total+remains != 50 {
+			t.Fatalf("%s: failed bucket hit, remains %d total %d", name, remains, total)
+		}
+
+		err = limiter.Lock()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mustLock, err := limiter.MustLock()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ttl, err := limiter.AvailableIn()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !mustLock || ttl <= 0 || ttl > 100*time.Millisecond {
+			t.Fatalf("%s: failed bucket lock, available in %v", name, ttl)
+		}
+
+		time.Sleep(ttl + 10*time.Millisecond)
+		mustLock, err = limiter.MustLock()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if mustLock {
+			t.Fatalf("%s: failed bucket refill", name)
+		}
+	}
+}
+
+func TestGCRA(t *testing.T) {
+	for name, c := range map[string]cache.Cache{"redis": redisCache(), "memory": memoryCache(), "file": cache.NewFileCache("gcra", t.TempDir())} {
+		// 10 attempts per second with bursts of 3
+		limiter, err := cache.NewGCRALimiter("test-gcra", 10, time.Second, 3, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = limiter.Reset()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 3; i++ {
+			allowed, _, resetAfter, err := limiter.Allow()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !allowed || resetAfter <= 0 || resetAfter > 300*time.Millisecond {
+				t.Fatalf("%s: failed burst allow %d, reset after %v", name, i, resetAfter)
+			}
+		}
+
+		allowed, retryAfter, resetAfter, err := limiter.Allow()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if allowed || retryAfter <= 0 || retryAfter > 100*time.Millisecond || resetAfter <= 200*time.Millisecond {
+			t.Fatalf("%s: failed deny, retry after %v reset after %v", name, retryAfter, resetAfter)
+		}
+
+		mustLock, err := limiter.MustLock()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		total, err := limiter.TotalAttempts()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !mustLock || total != 3 {
+			t.Fatalf("%s: failed gcra lock, total %d", name, total)
+		}
+
+		time.Sleep(retryAfter + 5*time.Millisecond)
+		allowed, _, _, err = limiter.Allow()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !allowed {
+			t.Fatalf("%s: failed allow after retry", name)
+		}
+
+		err = limiter.Lock()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		remains, err := limiter.RetriesLeft()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		available, err := limiter.AvailableIn()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if remains != 0 || available <= 0 || available > 100*time.Millisecond {
+			t.Fatalf("%s: failed lock, remains %d available in %v", name, remains, available)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, _, _, err := limiter.WithContext(ctx).Allow(); err == nil {
+			t.Fatalf("%s: context bound limiter ignored canceled context", name)
+		}
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	limiters := map[string]func(key string, c cache.Cache) (cache.RateLimiter, error){
+		"fixed": func(key string, c cache.Cache) (cache.RateLimiter, error) {
+			return cache.NewRateLimiter(key, 5, time.Minute, c)
+		},
+		"sliding": func(key string, c cache.Cache) (cache.RateLimiter, error) {
+			return cache.NewRateLimiter(key, 5, time.Minute, c, cache.WithSlidingWindow())
+		},
+		"bucket": func(key string, c cache.Cache) (cache.RateLimiter, error) {
+			return cache.NewRateLimiter(key, 5, time.Minute, c, cache.WithTokenBucket(0))
+		},
+		"gcra": func(key string, c cache.Cache) (cache.RateLimiter, error) {
+			return cache.NewRateLimiter(key, 5, time.Minute, c, cache.WithGCRA(0))
+		},
+	}
+
+	for name, create := range limiters {
+		for driver, c := range map[string]cache.Cache{"redis": redisCache(), "memory": memoryCache()} {
+			limiter, err := create("test-attempt-"+name, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = limiter.Reset()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var allowed int
+			mutex := sync.Mutex{}
+			wg := sync.WaitGroup{}
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					res, err := limiter.Attempt()
+					if err != nil {
+						t.Error(err)
+						return
+					}
+
+					if res.Allowed {
+						mutex.Lock()
+						allowed++
+						mutex.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			if allowed != 5 {
+				t.Fatalf("%s %s: %d concurrent attempts allowed", name, driver, allowed)
+			}
+
+			res, err := limiter.Attempt()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Allowed || res.RetriesLeft != 0 || res.RetryAfter <= 0 || res.ResetAfter <= 0 || res.ResetAfter > 2*time.Minute {
+				t.Fatalf("%s %s: failed denied attempt %+v", name, driver, res)
+			}
+
+			err = limiter.Reset()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err = limiter.Attempt()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !res.Allowed || res.RetriesLeft != 4 || res.RetryAfter != 0 {
+				t.Fatalf("%s %s: failed allowed attempt %+v", name, driver, res)
+			}
+		}
+	}
+}
+
+func TestKeyedRateLimiter(t *testing.T) {
+	options := map[string][]cache.LimiterOption{
+		"fixed":   nil,
+		"sliding": {cache.WithSlidingWindow()},
+		"bucket":  {cache.WithTokenBucket(0)},
+		"gcra":    {cache.WithGCRA(0)},
+	}
+
+	for name, opts := range options {
+		for driver, c := range map[string]cache.Cache{"redis": redisCache(), "memory": memoryCache()} {
+			keyed, err := cache.NewKeyedRateLimiter("test-keyed-"+name, 3, time.Minute, c, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, identity := range []string{"10.0.0.1", "10.0.0.2"} {
+				if err := keyed.For(identity).Clear(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			remains, err := keyed.For("10.0.0.1").RetriesLeft()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			total, err := keyed.For("10.0.0.1").TotalAttempts()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if remains != 3 || total != 0 {
+				t.Fatalf("%s %s: failed new identity, remains %d total %d", name, driver, remains, total)
+			}
+
+			for i := 0; i < 3; i++ {
+				res, err := keyed.Attempt("10.0.0.1")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !res.Allowed {
+					t.Fatalf("%s %s: failed attempt %d", name, driver, i)
+				}
+			}
+
+			res, err := keyed.Attempt("10.0.0.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Allowed {
+				t.Fatalf("%s %s: failed identity limit", name, driver)
+			}
+
+			err = keyed.For("10.0.0.2").Hit()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err = keyed.Attempt("10.0.0.2")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !res.Allowed || res.RetriesLeft != 1 {
+				t.Fatalf("%s %s: failed other identity %+v", name, driver, res)
+			}
+		}
+	}
+
+	_, err := cache.NewKeyedRateLimiter("test-keyed-invalid", 3, 0, memoryCache(), cache.WithSlidingWindow())
+	if err == nil {
+		t.Fatal("failed invalid options")
+	}
+}
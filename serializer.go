@@ -0,0 +1,357 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Serializer interface for cache value encoders
+type Serializer interface {
+	// ID unique serializer identifier stored with encoded values
+	ID() byte
+	// Marshal encode value
+	Marshal(value any) ([]byte, error)
+	// Unmarshal decode data into dest pointer
+	Unmarshal(data []byte, dest any) error
+}
+
+// GobSerializer create gob serializer, default serializer of drivers
+//
+// custom types must registered with gob.Register to decode as their own type
+func GobSerializer() Serializer {
+	return gobSerializer{}
+}
+
+// JSONSerializer create json serializer
+func JSONSerializer() Serializer {
+	return jsonSerializer{}
+}
+
+// MsgpackSerializer create MessagePack serializer
+func MsgpackSerializer() Serializer {
+	return msgpackSerializer{}
+}
+
+// builtin serializers by id
+var serializers = map[byte]Serializer{
+	gobSerializer{}.ID():     gobSerializer{},
+	jsonSerializer{}.ID():    jsonSerializer{},
+	msgpackSerializer{}.ID(): msgpackSerializer{},
+}
+
+// gob value wrapper, wrapping value as interface keep value concrete type
+type gobValue struct {
+	Value any
+}
+
+type gobSerializer struct{}
+
+func (gobSerializer) ID() byte {
+	return 'g'
+}
+
+func (gobSerializer) Marshal(value any) ([]byte, error) {
+	b := bytes.Buffer{}
+	if err := gob.NewEncoder(&b).Encode(gobValue{Value: value}); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (gobSerializer) Unmarshal(data []byte, dest any) error {
+	v := gobValue{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		return err
+	}
+	return assign(v.Value, dest)
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) ID() byte {
+	return 'j'
+}
+
+func (jsonSerializer) Marshal(value any) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonSerializer) Unmarshal(data []byte, dest any) error {
+	return json.Unmarshal(data, dest)
+}
+
+type msgpackSerializer struct{}
+
+func (msgpackSerializer) ID() byte {
+	return 'm'
+}
+
+func (msgpackSerializer) Marshal(value any) ([]byte, error) {
+	return msgpack.Marshal(value)
+}
+
+func (msgpackSerializer) Unmarshal(data []byte, dest any) error {
+	return msgpack.Unmarshal(data, dest)
+}
+
+// marker of serialized values, followed by serializer id and serialized data
+const valueMarker byte = 0
+
+// markers of numeric values, followed by plain decimal number, so drivers can change them atomically.
+// values without marker (e.g. stored by older versions) are plain strings
+const (
+	intMarker   byte = 2
+	floatMarker byte = 3
+)
+
+// serializerOf get serializer of id, s used if its id matches
+func serializerOf(s Serializer, id byte) (Serializer, error) {
+	if s.ID() == id {
+		return s, nil
+	}
+
+	if builtin, ok := serializers[id]; ok {
+		return builtin, nil
+	}
+	return nil, fmt.Errorf("unknown serializer %q", id)
+}
+
+// encodeValue encode value for storing in cache
+func encodeValue(s Serializer, value any) ([]byte, error) {
+	switch v := value.(type) {
+	case int:
+		return strconv.AppendInt([]byte{intMarker}, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt([]byte{intMarker}, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt([]byte{intMarker}, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt([]byte{intMarker}, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt([]byte{intMarker}, v, 10), nil
+	case uint:
+		return strconv.AppendUint([]byte{intMarker}, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint([]byte{intMarker}, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint([]byte{intMarker}, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint([]byte{intMarker}, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint([]byte{intMarker}, v, 10), nil
+	case float32:
+		return strconv.AppendFloat([]byte{floatMarker}, float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.AppendFloat([]byte{floatMarker}, v, 'f', -1, 64), nil
+	}
+
+	data, err := s.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{valueMarker, s.ID()}, data...), nil
+}
+
+// decodeValue decode stored value, integers decoded as int64 (uint64 if too big) and floats as float64.
+// plain values (e.g. stored by older versions) decoded as string unchanged
+func decodeValue(s Serializer, data []byte) (any, error) {
+	if len(data) >= 2 && data[0] == valueMarker {
+		serializer, err := serializerOf(s, data[1])
+		if err != nil {
+			return nil, err
+		}
+
+		var v any
+		if err := serializer.Unmarshal(data[2:], &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+
+	if isNumber(data) {
+		return decodeNumber(data)
+	}
+	return string(data), nil
+}
+
+// isNumber check if data is marked numeric value
+func isNumber(data []byte) bool {
+	return len(data) >= 2 && (data[0] == intMarker || data[0] == floatMarker)
+}
+
+// decodeNumber decode marked numeric value
+func decodeNumber(data []byte) (any, error) {
+	str := string(data[1:])
+	if data[0] == floatMarker {
+		if v, err := strconv.ParseFloat(str, 64); err != nil {
+			return nil, err
+		} else {
+			return v, nil
+		}
+	}
+
+	if v, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return v, nil
+	}
+
+	if v, err := strconv.ParseUint(str, 10, 64); err != nil {
+		return nil, err
+	} else {
+		return v, nil
+	}
+}
+
+// numberOf get number of stored value for numeric operations, marked numbers decoded and plain values
+// (e.g. stored by older versions or changed by redis scripts) parsed. return false for non numeric values
+func numberOf(data []byte) (any, bool) {
+	if isNumber(data) {
+		v, err := decodeNumber(data)
+		return v, err == nil
+	}
+
+	if len(data) > 0 && data[0] == valueMarker {
+		return nil, false
+	}
+	return parseNumber(string(data))
+}
+
+// parseNumber parse plain value as int64, uint64 or float64, return false for non numeric values
+func parseNumber(str string) (any, bool) {
+	if v, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return v, true
+	}
+
+	if v, err := strconv.ParseUint(str, 10, 64); err == nil {
+		return v, true
+	}
+
+	if v, err := strconv.ParseFloat(str, 64); err == nil {
+		return v, true
+	}
+	return nil, false
+}
+
+// plainForm get stored form of plain value read from cache as string (e.g. stored by older versions),
+// return false for strings that can not be stored plain. used by compare and swap, so values read from cache always match
+func plainForm(value any) ([]byte, bool) {
+	str, ok := value.(string)
+	if !ok || (len(str) > 0 && str[0] <= floatMarker) {
+		return nil, false
+	}
+	return []byte(str), true
+}
+
+// matchStored check if stored data equals encoded old value or plain form of old value
+func matchStored(data []byte, encodedOld []byte, old any) bool {
+	if bytes.Equal(data, encodedOld) {
+		return true
+	}
+
+	plain, ok := plainForm(old)
+	return ok && bytes.Equal(data, plain)
+}
+
+// decodeInto decode stored value into dest pointer
+func decodeInto(s Serializer, data []byte, dest any) error {
+	if len(data) >= 2 && data[0] == valueMarker {
+		serializer, err := serializerOf(s, data[1])
+		if err != nil {
+			return err
+		}
+		return serializer.Unmarshal(data[2:], dest)
+	}
+
+	if isNumber(data) {
+		v, err := decodeNumber(data)
+		if err != nil {
+			return err
+		}
+		return assign(v, dest)
+	}
+	return assign(string(data), dest)
+}
+
+// assign set value to dest pointer, numeric values and plain numeric strings converted to numeric dest type
+func assign(value any, dest any) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("destination must be non-nil pointer, %T given", dest)
+	}
+
+	target := rv.Elem()
+	if value == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	if str, ok := value.(string); ok && isNumeric(target.Kind()) {
+		if n, ok := parseNumber(str); ok {
+			v = reflect.ValueOf(n)
+		}
+	}
+
+	switch {
+	case v.Type().AssignableTo(target.Type()):
+		target.Set(v)
+	case isNumeric(v.Kind()) && isNumeric(target.Kind()):
+		target.Set(v.Convert(target.Type()))
+	default:
+		return fmt.Errorf("cannot assign %T to %s", value, target.Type())
+	}
+	return nil
+}
+
+func isNumeric(kind reflect.Kind) bool {
+	return (kind >= reflect.Int && kind <= reflect.Uint64) || kind == reflect.Float32 || kind == reflect.Float64
+}
+
+// toInt64 get value of integer item, plain numbers read from cache as strings parsed.
+// return false for non integer values
+func toInt64(value any) (int64, bool) {
+	if value == nil {
+		return 0, false
+	}
+
+	if str, ok := value.(string); ok {
+		if value, ok = parseNumber(str); !ok {
+			return 0, false
+		}
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		return v.Int(), true
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		return int64(v.Uint()), true
+	}
+	return 0, false
+}
+
+// toFloat64 get value of numeric item, plain numbers read from cache as strings parsed.
+// return false for non numeric values
+func toFloat64(value any) (float64, bool) {
+	if str, ok := value.(string); ok {
+		if value, ok = parseNumber(str); !ok {
+			return 0, false
+		}
+	}
+
+	if i, ok := toInt64(value); ok {
+		return float64(i), true
+	}
+
+	if value != nil {
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64 {
+			return v.Float(), true
+		}
+	}
+	return 0, false
+}
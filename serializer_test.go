@@ -0,0 +1,144 @@
+package cache_test
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bopher/cache"
+	"github.com/go-redis/redis/v8"
+)
+
+type serializerUser struct {
+	Name string
+	Age  int
+}
+
+func init() {
+	gob.Register(serializerUser{})
+}
+
+func TestSerializers(t *testing.T) {
+	user := serializerUser{Name: "kim", Age: 30}
+	for _, s := range []cache.Serializer{cache.GobSerializer(), cache.JSONSerializer(), cache.MsgpackSerializer()} {
+		drivers := []cache.Cache{
+			cache.NewFileCache("serializer", t.TempDir(), cache.WithSerializer(s)),
+			cache.NewRedisCache("serializer", redis.Options{Addr: "localhost:6379"}, cache.WithSerializer(s)),
+			cache.NewMemoryCache(0, 0, 0, cache.WithSerializer(s)),
+		}
+
+		values := make([]any, 0)
+		for _, c := range drivers {
+			if err := c.Put("user", user, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := c.Get("user")
+			if err != nil {
+				t.Fatal(err)
+			}
+			values = append(values, v)
+		}
+
+		for _, v := range values[1:] {
+			if !reflect.DeepEqual(values[0], v) {
+				t.Fatalf("serializer %c: drivers returned different values %#v, %#v", s.ID(), values[0], v)
+			}
+		}
+
+		if s.ID() == cache.GobSerializer().ID() && values[0] != user {
+			t.Fatalf("failed gob round-trip %#v", values[0])
+		}
+	}
+}
+
+func TestSerializerNumbers(t *testing.T) {
+	c := cache.NewRedisCache("serializer", redis.Options{Addr: "localhost:6379"}, cache.WithSerializer(cache.JSONSerializer()))
+	err := c.Put("number", 5, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Increment("number", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := c.Get("number")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != int64(7) {
+		t.Fatalf("failed numeric value %#v", v)
+	}
+
+	err = c.Put("text", "7", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err = c.Get("text")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "7" {
+		t.Fatalf("failed numeric string value %#v", v)
+	}
+
+	if _, err := c.Increment("text", 1); !errors.Is(err, cache.ErrNotNumeric) {
+		t.Fatal("serialized numeric string incremented", err)
+	}
+
+	// plain value stored by older versions
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	err = client.Set(context.Background(), "serializer-code", "01234", time.Minute).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err = c.Get("code")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "01234" {
+		t.Fatalf("plain value changed %#v", v)
+	}
+
+	swapped, err := c.CompareAndSwap("code", v, "56789")
+	if err != nil || !swapped {
+		t.Fatal("failed swap plain value with read value", err)
+	}
+
+	err = client.Set(context.Background(), "serializer-code", "01234", time.Minute).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Set(context.Background(), "serializer-legacy", "5", time.Minute).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Increment("legacy", 1); err != nil {
+		t.Fatal("failed increment plain number", err)
+	}
+
+	if v, err := c.Get("legacy"); err != nil || v != int64(6) {
+		t.Fatalf("failed incremented plain number %#v %v", v, err)
+	}
+
+	typed, _, err := cache.NewTyped[int](c, 0).Get("code")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if typed != 1234 {
+		t.Fatalf("failed typed plain number %d", typed)
+	}
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Tagger interface for tag based invalidation of cache items
+type Tagger interface {
+	// Tags get cache of items tagged with all of tags
+	Tags(tags ...string) TaggedCache
+	// FlushTags invalidate all items tagged with any of tags
+	FlushTags(tags ...string) error
+	// WithContext get a copy of tagger that run cache operations with ctx
+	WithContext(ctx context.Context) Tagger
+}
+
+// TaggedCache interface for items of a tag set, items must read with same tags they put with
+type TaggedCache interface {
+	// Put a new value to cache
+	Put(key string, value any, ttl time.Duration) error
+	// PutForever put value with infinite ttl
+	PutForever(key string, value any) error
+	// PutMany put multiple values to cache with same ttl
+	PutMany(values map[string]any, ttl time.Duration) error
+	// Get item from cache
+	Get(key string) (any, error)
+	// GetMany get multiple items from cache, missing items not included in result
+	GetMany(keys []string) (map[string]any, error)
+	// Exists check if item exists in cache
+	Exists(key string) (bool, error)
+	// Forget delete Item from cache
+	Forget(key string) error
+	// Pull item from cache and remove it
+	Pull(key string) (any, error)
+	// Remember get item from cache or run loader and put result with ttl on cache miss
+	Remember(key string, ttl time.Duration, loader func() (any, error)) (any, error)
+	// RememberForever get item from cache or run loader and put result with infinite ttl on cache miss
+	RememberForever(key string, loader func() (any, error)) (any, error)
+	// Flush invalidate all items tagged with any of tags
+	Flush() error
+}
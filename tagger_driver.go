@@ -0,0 +1,285 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bopher/caster"
+	"github.com/bopher/utils"
+)
+
+const (
+	// prefix of tag version items
+	tagVersionPrefix = "tag:"
+	// prefix of tagged items
+	taggedPrefix = "tagged:"
+	// ttl of tag version items, items of expired versions treated as invalidated
+	tagVersionTTL = 365 * 24 * time.Hour
+)
+
+type tgDriver struct {
+	cache  Cache
+	loader Loader
+}
+
+func (tg tgDriver) err(pattern string, params ...any) error {
+	return utils.TaggedError([]string{"Tagger"}, pattern, params...)
+}
+
+func (tg *tgDriver) init(cache Cache, lockTTL time.Duration) {
+	tg.cache = cache
+	tg.loader = NewLoader(cache, lockTTL)
+}
+
+// newVersion generate random tag version
+func (tg tgDriver) newVersion() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", tg.err("%w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// versions get current version of tags, missing versions created
+func (tg tgDriver) versions(tags []string) ([]string, error) {
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = tagVersionPrefix + tag
+	}
+
+	items, err := tg.cache.GetMany(keys)
+	if err != nil {
+		return nil, tg.err("%w", err)
+	}
+
+	res := make([]string, len(tags))
+	for i, key := range keys {
+		if v, ok := items[key]; ok && v != nil {
+			res[i] = caster.NewCaster(v).StringSafe("")
+			continue
+		}
+
+		version, err := tg.newVersion()
+		if err != nil {
+			return nil, err
+		}
+
+		// other process may create version at same time
+		if ok, err := tg.cache.Add(key, version, tagVersionTTL); err != nil {
+			return nil, tg.err("%w", err)
+		} else if !ok {
+			v, err := tg.cache.Get(key)
+			if err != nil {
+				return nil, tg.err("%w", err)
+			}
+			version = caster.NewCaster(v).StringSafe("")
+		}
+		res[i] = version
+	}
+	return res, nil
+}
+
+func (tg tgDriver) Tags(tags ...string) TaggedCache {
+	unique := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		if _, ok := seen[tag]; !ok {
+			seen[tag] = struct{}{}
+			unique = append(unique, tag)
+		}
+	}
+	sort.Strings(unique)
+	return &tgSet{driver: tg, tags: unique}
+}
+
+// FlushTags replace tags versions, items of old versions never read again and removed by cache expiration
+func (tg tgDriver) FlushTags(tags ...string) error {
+	values := make(map[string]any, len(tags))
+	for _, tag := range tags {
+		version, err := tg.newVersion()
+		if err != nil {
+			return err
+		}
+		values[tagVersionPrefix+tag] = version
+	}
+
+	if err := tg.cache.PutMany(values, tagVersionTTL); err != nil {
+		return tg.err("%w", err)
+	}
+	return nil
+}
+
+func (tg tgDriver) WithContext(ctx context.Context) Tagger {
+	tg.cache = tg.cache.WithContext(ctx)
+	tg.loader = tg.loader.WithContext(ctx)
+	return &tg
+}
+
+type tgSet struct {
+	driver tgDriver
+	tags   []string
+}
+
+// namespace get key prefix of current tags versions
+func (ts tgSet) namespace() (string, error) {
+	versions, err := ts.driver.versions(ts.tags)
+	if err != nil {
+		return "", err
+	}
+
+	parts := make([]string, len(ts.tags))
+	for i, tag := range ts.tags {
+		parts[i] = tag + "=" + versions[i]
+	}
+	sum := sha1.Sum([]byte(strings.Join(parts, "|")))
+	return taggedPrefix + hex.EncodeToString(sum[:]) + ":", nil
+}
+
+// key get tagged key of item
+func (ts tgSet) key(key string) (string, error) {
+	ns, err := ts.namespace()
+	if err != nil {
+		return "", err
+	}
+	return ns + key, nil
+}
+
+func (ts tgSet) Put(key string, value any, ttl time.Duration) error {
+	k, err := ts.key(key)
+	if err != nil {
+		return err
+	}
+
+	if err := ts.driver.cache.Put(k, value, ttl); err != nil {
+		return ts.driver.err("%w", err)
+	}
+	return nil
+}
+
+func (ts tgSet) PutForever(key string, value any) error {
+	k, err := ts.key(key)
+	if err != nil {
+		return err
+	}
+
+	if err := ts.driver.cache.PutForever(k, value); err != nil {
+		return ts.driver.err("%w", err)
+	}
+	return nil
+}
+
+func (ts tgSet) PutMany(values map[string]any, ttl time.Duration) error {
+	ns, err := ts.namespace()
+	if err != nil {
+		return err
+	}
+
+	tagged := make(map[string]any, len(values))
+	for k, v := range values {
+		tagged[ns+k] = v
+	}
+
+	if err := ts.driver.cache.PutMany(tagged, ttl); err != nil {
+		return ts.driver.err("%w", err)
+	}
+	return nil
+}
+
+func (ts tgSet) Get(key string) (any, error) {
+	k, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, err := ts.driver.cache.Get(k); err != nil {
+		return nil, ts.driver.err("%w", err)
+	} else {
+		return v, nil
+	}
+}
+
+func (ts tgSet) GetMany(keys []string) (map[string]any, error) {
+	ns, err := ts.namespace()
+	if err != nil {
+		return nil, err
+	}
+
+	tagged := make([]string, len(keys))
+	for i, k := range keys {
+		tagged[i] = ns + k
+	}
+
+	items, err := ts.driver.cache.GetMany(tagged)
+	if err != nil {
+		return nil, ts.driver.err("%w", err)
+	}
+
+	res := make(map[string]any, len(items))
+	for k, v := range items {
+		res[strings.TrimPrefix(k, ns)] = v
+	}
+	return res, nil
+}
+
+func (ts tgSet) Exists(key string) (bool, error) {
+	k, err := ts.key(key)
+	if err != nil {
+		return false, err
+	}
+
+	if exists, err := ts.driver.cache.Exists(k); err != nil {
+		return false, ts.driver.err("%w", err)
+	} else {
+		return exists, nil
+	}
+}
+
+func (ts tgSet) Forget(key string) error {
+	k, err := ts.key(key)
+	if err != nil {
+		return err
+	}
+
+	if err := ts.driver.cache.Forget(k); err != nil {
+		return ts.driver.err("%w", err)
+	}
+	return nil
+}
+
+func (ts tgSet) Pull(key string) (any, error) {
+	k, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, err := ts.driver.cache.Pull(k); err != nil {
+		return nil, ts.driver.err("%w", err)
+	} else {
+		return v, nil
+	}
+}
+
+func (ts tgSet) Remember(key string, ttl time.Duration, loader func() (any, error)) (any, error) {
+	k, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+	return ts.driver.loader.Remember(k, ttl, loader)
+}
+
+func (ts tgSet) RememberForever(key string, loader func() (any, error)) (any, error) {
+	k, err := ts.key(key)
+	if err != nil {
+		return nil, err
+	}
+	return ts.driver.loader.RememberForever(k, loader)
+}
+
+func (ts tgSet) Flush() error {
+	return ts.driver.FlushTags(ts.tags...)
+}
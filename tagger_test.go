@@ -0,0 +1,106 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bopher/cache"
+)
+
+func TestTagger(t *testing.T) {
+	for _, c := range []cache.Cache{redisCache(), cache.NewMemoryCache(0, 0, 0), cache.NewFileCache("tags", t.TempDir())} {
+		tagger := cache.NewTagger(c, 0)
+		err := tagger.Tags("product:42", "catalog").Put("fragment", "<p>42</p>", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = tagger.Tags("product:43", "catalog").Put("fragment", "<p>43</p>", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		v, err := tagger.Tags("catalog", "product:42").Get("fragment")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v != "<p>42</p>" {
+			t.Fatalf("failed tagged get %v", v)
+		}
+
+		err = tagger.FlushTags("product:42")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exists, err := tagger.Tags("product:42", "catalog").Exists("fragment")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exists {
+			t.Fatal("failed flush tags")
+		}
+
+		v, err = tagger.Tags("product:43", "catalog").Get("fragment")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v != "<p>43</p>" {
+			t.Fatalf("unrelated tag flushed %v", v)
+		}
+
+		err = tagger.Tags("catalog").Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exists, err = tagger.Tags("product:43", "catalog").Exists("fragment")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exists {
+			t.Fatal("failed flush shared tag")
+		}
+	}
+}
+
+func TestTaggerRemember(t *testing.T) {
+	tagger := cache.NewTagger(cache.NewMemoryCache(0, 0, 0), 0)
+	calls := 0
+	loader := func() (any, error) {
+		calls++
+		return "users", nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := tagger.Tags("users").Remember("list", time.Minute, loader); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tagger.FlushTags("users"); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := tagger.Tags("users").Remember("list", time.Minute, loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "users" || calls != 2 {
+		t.Fatalf("failed tagged remember, loader called %d times", calls)
+	}
+
+	items, err := tagger.Tags("users").GetMany([]string{"list", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 1 || items["list"] != "users" {
+		t.Fatalf("failed tagged get many %v", items)
+	}
+}
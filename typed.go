@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Typed interface for generic cache wrapper with typed values
+type Typed[T any] interface {
+	// Put a new value to cache
+	Put(key string, value T, ttl time.Duration) error
+	// PutForever put value with infinite ttl
+	PutForever(key string, value T) error
+	// PutMany put multiple values to cache with same ttl
+	PutMany(values map[string]T, ttl time.Duration) error
+	// Get item from cache, return false if item not exists
+	Get(key string) (T, bool, error)
+	// GetMany get multiple items from cache, missing items not included in result
+	GetMany(keys []string) (map[string]T, error)
+	// Remember get item from cache or run loader and put result with ttl on cache miss
+	Remember(key string, ttl time.Duration, loader func() (T, error)) (T, error)
+	// RememberForever get item from cache or run loader and put result with infinite ttl on cache miss
+	RememberForever(key string, loader func() (T, error)) (T, error)
+	// WithContext get a copy of typed cache that run cache operations with ctx
+	WithContext(ctx context.Context) Typed[T]
+}
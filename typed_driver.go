@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bopher/utils"
+)
+
+type tpDriver[T any] struct {
+	cache  Cache
+	loader Loader
+}
+
+func (td tpDriver[T]) err(key string, pattern string, params ...any) error {
+	return utils.TaggedError([]string{"Typed", key}, pattern, params...)
+}
+
+func (td *tpDriver[T]) init(cache Cache, lockTTL time.Duration) {
+	td.cache = cache
+	// typed values decoded by wrapper, so loaded values returned as is
+	loader := new(lDriver)
+	loader.init(cache, lockTTL)
+	loader.normalize = false
+	td.loader = loader
+}
+
+// decode decode raw data with driver codec or assign decoded value for drivers without raw read support
+func (td tpDriver[T]) decode(key string, data []byte, c *codec, value any) (T, error) {
+	var res T
+	var err error
+	if c != nil {
+		err = c.decodeInto(data, &res)
+	} else {
+		err = assign(value, &res)
+	}
+
+	if err != nil {
+		err = td.err(key, "%w", err)
+	}
+	return res, err
+}
+
+func (td tpDriver[T]) Put(key string, value T, ttl time.Duration) error {
+	return td.cache.Put(key, value, ttl)
+}
+
+func (td tpDriver[T]) PutForever(key string, value T) error {
+	return td.cache.PutForever(key, value)
+}
+
+func (td tpDriver[T]) PutMany(values map[string]T, ttl time.Duration) error {
+	items := make(map[string]any, len(values))
+	for k, v := range values {
+		items[k] = v
+	}
+	return td.cache.PutMany(items, ttl)
+}
+
+func (td tpDriver[T]) Get(key string) (T, bool, error) {
+	var zero T
+	if raw, ok := td.cache.(rawGetter); ok {
+		data, c, err := raw.getRaw(key)
+		if !errors.Is(err, errRawUnsupported) {
+			if err != nil || data == nil {
+				return zero, false, err
+			}
+
+			v, err := td.decode(key, data, &c, nil)
+			return v, err == nil, err
+		}
+	}
+
+	value, err := td.cache.Get(key)
+	if err != nil || value == nil {
+		return zero, false, err
+	}
+
+	v, err := td.decode(key, nil, nil, value)
+	return v, err == nil, err
+}
+
+func (td tpDriver[T]) GetMany(keys []string) (map[string]T, error) {
+	res := make(map[string]T)
+	if raw, ok := td.cache.(rawGetter); ok {
+		items, err := raw.getManyRaw(keys)
+		if !errors.Is(err, errRawUnsupported) {
+			if err != nil {
+				return nil, err
+			}
+
+			for k, item := range items {
+				if res[k], err = td.decode(k, item.data, &item.codec, nil); err != nil {
+					return nil, err
+				}
+			}
+			return res, nil
+		}
+	}
+
+	items, err := td.cache.GetMany(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, value := range items {
+		if res[k], err = td.decode(k, nil, nil, value); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// remember get typed item or run remember and read loaded item
+func (td tpDriver[T]) remember(key string, remember func() (any, error)) (T, error) {
+	if v, exists, err := td.Get(key); err != nil || exists {
+		return v, err
+	}
+
+	value, err := remember()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	// value loaded by other process read from cache with serializer type
+	if v, ok := value.(T); ok {
+		return v, nil
+	}
+
+	v, _, err := td.Get(key)
+	return v, err
+}
+
+func (td tpDriver[T]) Remember(key string, ttl time.Duration, loader func() (T, error)) (T, error) {
+	return td.remember(key, func() (any, error) {
+		return td.loader.Remember(key, ttl, func() (any, error) {
+			return loader()
+		})
+	})
+}
+
+func (td tpDriver[T]) RememberForever(key string, loader func() (T, error)) (T, error) {
+	return td.remember(key, func() (any, error) {
+		return td.loader.RememberForever(key, func() (any, error) {
+			return loader()
+		})
+	})
+}
+
+func (td tpDriver[T]) WithContext(ctx context.Context) Typed[T] {
+	td.cache = td.cache.WithContext(ctx)
+	td.loader = td.loader.WithContext(ctx)
+	return &td
+}
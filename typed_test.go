@@ -0,0 +1,173 @@
+package cache_test
+
+import (
+	"encoding/gob"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bopher/cache"
+	"github.com/go-redis/redis/v8"
+)
+
+type typedProduct struct {
+	Name string
+	Tags []string
+}
+
+func init() {
+	gob.Register(typedProduct{})
+}
+
+func TestTypedGetPut(t *testing.T) {
+	product := typedProduct{Name: "book", Tags: []string{"paper", "new"}}
+	for _, s := range []cache.Serializer{cache.GobSerializer(), cache.JSONSerializer(), cache.MsgpackSerializer()} {
+		drivers := []cache.Cache{
+			cache.NewFileCache("typed", t.TempDir(), cache.WithSerializer(s)),
+			cache.NewRedisCache("typed", redis.Options{Addr: "localhost:6379"}, cache.WithSerializer(s)),
+			cache.NewMemoryCache(0, 0, 0, cache.WithSerializer(s)),
+		}
+
+		for _, c := range drivers {
+			typed := cache.NewTyped[typedProduct](c, 0)
+			err := typed.Put("product", product, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v, exists, err := typed.Get("product")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !exists || !reflect.DeepEqual(v, product) {
+				t.Fatalf("serializer %c: failed typed get %#v", s.ID(), v)
+			}
+
+			_, exists, err = typed.Get("non-exists")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if exists {
+				t.Fatal("failed exists check!")
+			}
+		}
+	}
+}
+
+func TestTypedNumbers(t *testing.T) {
+	typed := cache.NewTyped[int](redisCache(), 0)
+	err := typed.Put("typed-int", 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = redisCache().Increment("typed-int", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, _, err := typed.Get("typed-int")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != 5 {
+		t.Fatalf("failed typed number %d", v)
+	}
+}
+
+func TestTypedMany(t *testing.T) {
+	typed := cache.NewTyped[[]string](redisCache(), 0)
+	err := typed.PutMany(map[string][]string{"typed-a": {"a"}, "typed-b": {"b", "c"}}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := typed.GetMany([]string{"typed-a", "typed-b", "typed-c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 || !reflect.DeepEqual(res["typed-b"], []string{"b", "c"}) {
+		t.Fatalf("failed typed get many %#v", res)
+	}
+}
+
+func TestTypedRemember(t *testing.T) {
+	c := cache.NewTieredCache(
+		cache.NewMemoryCache(0, 0, 0, cache.WithSerializer(cache.JSONSerializer())),
+		cache.NewMemoryCache(0, 0, 0, cache.WithSerializer(cache.JSONSerializer())),
+		time.Second,
+	)
+	typed := cache.NewTyped[typedProduct](c, 0)
+
+	calls := 0
+	for i := 0; i < 2; i++ {
+		v, err := typed.Remember("product", time.Minute, func() (typedProduct, error) {
+			calls++
+			return typedProduct{Name: "pen"}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Name != "pen" {
+			t.Fatalf("failed typed remember %#v", v)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("loader called %d times", calls)
+	}
+}
+
+// typedCustomSerializer json serializer with custom identifier
+type typedCustomSerializer struct{}
+
+func (typedCustomSerializer) ID() byte {
+	return 'x'
+}
+
+func (typedCustomSerializer) Marshal(value any) ([]byte, error) {
+	return cache.JSONSerializer().Marshal(value)
+}
+
+func (typedCustomSerializer) Unmarshal(data []byte, dest any) error {
+	return cache.JSONSerializer().Unmarshal(data, dest)
+}
+
+func TestTypedTieredMany(t *testing.T) {
+	local := cache.NewMemoryCache(0, 0, 0, cache.WithSerializer(typedCustomSerializer{}))
+	remote := redisCache()
+	typed := cache.NewTyped[[]string](cache.NewTieredCache(local, remote, 10*time.Second), 0)
+
+	err := local.Put("typed-local", []string{"a"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = remote.Put("typed-remote", []string{"b", "c"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := typed.GetMany([]string{"typed-local", "typed-remote"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 || !reflect.DeepEqual(res["typed-local"], []string{"a"}) || !reflect.DeepEqual(res["typed-remote"], []string{"b", "c"}) {
+		t.Fatalf("failed typed tiered get many %#v", res)
+	}
+
+	ttl, err := local.TTL("typed-remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl <= 0 || ttl > time.Second {
+		t.Fatalf("local back-fill ttl exceeds remote ttl %v", ttl)
+	}
+}
@@ -1,6 +1,9 @@
 package cache
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // VerificationCode interface for verification code
 type VerificationCode interface {
@@ -18,4 +21,6 @@ type VerificationCode interface {
 	Exists() (bool, error)
 	// TTL get ttl
 	TTL() (time.Duration, error)
+	// WithContext get a copy of verification code that run cache operations with ctx
+	WithContext(ctx context.Context) VerificationCode
 }
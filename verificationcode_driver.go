@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/bopher/utils"
@@ -16,29 +18,23 @@ func (vc vcDriver) err(pattern string, params ...any) error {
 }
 
 func (vc vcDriver) notExistsErr() error {
-	return utils.TaggedError([]string{"VerificationCode", "NotExists", vc.key}, "%s not exists", vc.key)
+	return utils.TaggedError([]string{"VerificationCode", "NotExists", vc.key}, "%s not exists: %w", vc.key, ErrNotFound)
 }
 
 func (vc *vcDriver) init(key string, ttl time.Duration, cache Cache) error {
 	vc.key = key
 	vc.cache = cache
 
-	exists, err := cache.Exists(key)
-	if err != nil {
-		return vc.err(err.Error())
-	}
-
-	if !exists {
-		return cache.Put(key, "", ttl)
+	if _, err := cache.Add(key, "", ttl); err != nil {
+		return vc.err("%w", err)
 	}
-
 	return nil
 }
 
 func (vc vcDriver) Set(value string) error {
 	exists, err := vc.cache.Set(vc.key, value)
 	if err != nil {
-		return vc.err(err.Error())
+		return vc.err("%w", err)
 	}
 
 	if !exists {
@@ -49,7 +45,7 @@ func (vc vcDriver) Set(value string) error {
 
 func (vc vcDriver) Generate() (string, error) {
 	if val, err := utils.RandomStringFromCharset(5, "0123456789"); err != nil {
-		return "", vc.err(err.Error())
+		return "", vc.err("%w", err)
 	} else {
 		return val, vc.Set(val)
 	}
@@ -65,7 +61,7 @@ func (vc vcDriver) GenerateN(count uint) (string, error) {
 
 func (vc vcDriver) Clear() error {
 	if err := vc.cache.Forget(vc.key); err != nil {
-		return vc.err(err.Error())
+		return vc.err("%w", err)
 	}
 	return nil
 }
@@ -73,7 +69,7 @@ func (vc vcDriver) Clear() error {
 func (vc vcDriver) Get() (string, error) {
 	caster, err := vc.cache.Cast(vc.key)
 	if err != nil {
-		return "", vc.err(err.Error())
+		return "", vc.err("%w", err)
 	}
 
 	if caster.IsNil() {
@@ -82,7 +78,7 @@ func (vc vcDriver) Get() (string, error) {
 
 	v, err := caster.String()
 	if err != nil {
-		err = vc.err(err.Error())
+		err = vc.err("%w", err)
 	}
 
 	return v, err
@@ -91,15 +87,22 @@ func (vc vcDriver) Get() (string, error) {
 func (vc vcDriver) Exists() (bool, error) {
 	exists, err := vc.cache.Exists(vc.key)
 	if err != nil {
-		err = vc.err(err.Error())
+		err = vc.err("%w", err)
 	}
 	return exists, err
 }
 
 func (vc vcDriver) TTL() (time.Duration, error) {
-	if v, err := vc.cache.TTL(vc.key); err != nil {
-		return 0, vc.err(err.Error())
+	if v, err := vc.cache.TTL(vc.key); errors.Is(err, ErrNotFound) {
+		return 0, vc.notExistsErr()
+	} else if err != nil {
+		return 0, vc.err("%w", err)
 	} else {
 		return v, nil
 	}
 }
+
+func (vc vcDriver) WithContext(ctx context.Context) VerificationCode {
+	vc.cache = vc.cache.WithContext(ctx)
+	return &vc
+}
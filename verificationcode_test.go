@@ -1,6 +1,7 @@
 package cache_test
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -72,4 +73,8 @@ func TestClearAndExists(t *testing.T) {
 	if exists {
 		t.Fail()
 	}
+	_, err = vCode.TTL()
+	if !errors.Is(err, cache.ErrNotFound) {
+		t.Fatal("failed cleared ttl", err)
+	}
 }